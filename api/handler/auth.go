@@ -1,44 +1,62 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"user/api/models"
 	"user/pkg/check"
+	"user/pkg/generator"
+	"user/pkg/jwt"
+	"user/service"
+	"user/storage"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
+const magicLinkNonceCookie = "magic_link_nonce"
+
 // ChangePassword godoc
 // @Security     ApiKeyAuth
 // @Router       /user/password/change [PATCH]
 // @Summary      Change user password
-// @Description  Updates a user password with the provided old and new passwords.
+// @Description  Updates the authenticated user's password with the provided old and new passwords and revokes the user's other sessions. Accepts an access token or the password_change_token returned by /user/login for an expired password. Recently used passwords are rejected.
 // @Tags         Auth
 // @Accept       json
 // @Produce      json
 // @Param        user body models.ChangePassword true "user"
 // @Success      200  {object}  string
 // @Failure      400  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      404  {object}  models.Response
 // @Failure      500  {object}  models.Response
 func (h Handler) ChangePassword(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
 	var pass models.ChangePassword
 	if err := c.ShouldBindJSON(&pass); err != nil {
 		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err.Error())
 		return
 	}
-	if err := check.ValidatePassword(pass.OldPassword); err != nil {
-		handleResponseLog(c, h.Log, "error while validating old password", http.StatusBadRequest, err.Error())
+
+	user, err := h.Services.User().GetByID(c.Request.Context(), authInfo.UserID)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting user", http.StatusInternalServerError, err.Error())
 		return
 	}
-	if err := check.ValidatePassword(pass.NewPassword); err != nil {
-		handleResponseLog(c, h.Log, "error while validating new password", http.StatusBadRequest, err.Error())
+
+	if !h.checkPassword(c, pass.NewPassword, user.Mail, user.FirstName, user.LastName) {
 		return
 	}
 
-	msg, err := h.Services.Auth().ChangePassword(c.Request.Context(), pass)
+	msg, err := h.Services.Auth().ChangePassword(c.Request.Context(), authInfo, pass)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while changing password", http.StatusInternalServerError, err.Error())
+		h.handlePasswordChangeError(c, "error while changing password", err, http.StatusInternalServerError)
 		return
 	}
 
@@ -56,6 +74,7 @@ func (h Handler) ChangePassword(c *gin.Context) {
 // @Success      201  {object}  models.Response
 // @Failure      400  {object}  models.Response
 // @Failure      404  {object}  models.Response
+// @Failure      429  {object}  models.Response
 // @Failure      500  {object}  models.Response
 func (h Handler) ForgetPassword(c *gin.Context) {
 	loginReq := models.UserMail{}
@@ -69,9 +88,9 @@ func (h Handler) ForgetPassword(c *gin.Context) {
 		handleResponseLog(c, h.Log, "Email address is incorrect"+loginReq.Mail, http.StatusBadRequest, err.Error())
 		return
 	}
-	err := h.Services.Auth().UserLoginOtp(c.Request.Context(), loginReq)
+	err := h.Services.Auth().ForgetPassword(c.Request.Context(), loginReq)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error", http.StatusBadRequest, err)
+		handleResponseLog(c, h.Log, "error", otpErrorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
@@ -79,6 +98,18 @@ func (h Handler) ForgetPassword(c *gin.Context) {
 }
 
 
+// GetPasswordPolicy godoc
+// @Router       /user/password/policy [GET]
+// @Summary      Get the password policy
+// @Description  Returns the rules new passwords must follow, so clients can show them before submitting.
+// @Tags         Forgetpassword
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  check.PasswordPolicy
+func (h Handler) GetPasswordPolicy(c *gin.Context) {
+	handleResponseLog(c, h.Log, "Got password policy successfully", http.StatusOK, check.CurrentPasswordPolicy())
+}
+
 // ForgetPasswordReset godoc
 // @Router       /user/password/reset [POST]
 // @Summary      Reset forgotten password
@@ -89,6 +120,7 @@ func (h Handler) ForgetPassword(c *gin.Context) {
 // @Param        user body models.ForgetPassword true "user"
 // @Success      200  {object}  string
 // @Failure      400  {object}  models.Response
+// @Failure      429  {object}  models.Response
 // @Failure      500  {object}  models.Response
 func (h Handler) ForgetPasswordReset(c *gin.Context) {
 	var forget models.ForgetPassword
@@ -97,31 +129,94 @@ func (h Handler) ForgetPasswordReset(c *gin.Context) {
 		return
 	}
 
-	if err := check.ValidatePassword(forget.NewPassword); err != nil {
-		handleResponseLog(c, h.Log, "error while validating new password", http.StatusBadRequest, err.Error())
+	if !h.checkPassword(c, forget.NewPassword, forget.Mail) {
 		return
 	}
 
 	msg, err := h.Services.Auth().ForgetPasswordReset(c.Request.Context(), forget)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while resetting password", http.StatusInternalServerError, err.Error())
+		h.handlePasswordChangeError(c, "error while resetting password", err, otpErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
 	handleResponseLog(c, h.Log, "Password reset successfully", http.StatusOK, msg)
 }
 
+// UnlockAccount godoc
+// @Router       /user/login/unlock [GET]
+// @Summary      Unlock a locked account
+// @Description  Lifts a lockout after too many failed logins with the link mailed to the user.
+// @Tags         Login
+// @Accept       json
+// @Produce      json
+// @Param        token query string true "token"
+// @Success      200  {object}  string
+// @Failure      400  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h Handler) UnlockAccount(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		handleResponseLog(c, h.Log, "missing unlock token", http.StatusBadRequest, "token is required")
+		return
+	}
+
+	err := h.Services.Auth().UnlockWithToken(c.Request.Context(), token)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidUnlockToken) {
+			handleResponseLog(c, h.Log, "error while unlocking account", http.StatusUnauthorized, err.Error())
+			return
+		}
+		handleResponseLog(c, h.Log, "error while unlocking account", http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Account unlocked successfully", http.StatusOK, "Success")
+}
+
+// UnlockUser godoc
+// @Security     ApiKeyAuth
+// @Router       /user/{id}/unlock [POST]
+// @Summary      Unlock a user
+// @Description  Lifts a lockout of the user after too many failed logins.
+// @Tags         ChangeStatus
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "user ID"
+// @Success      200  {object}  string
+// @Failure      400  {object}  models.Response
+// @Failure      403  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h Handler) UnlockUser(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := h.Services.Auth().UnlockUser(c.Request.Context(), id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while unlocking user", http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	handleResponseLog(c, h.Log, "User unlocked successfully", http.StatusOK, "Unlocked: "+id)
+}
+
 // ChangeStatus godoc
 // @Security     ApiKeyAuth
 // @Router       /user/status [PATCH]
 // @Summary      Change user status
-// @Description  Updates the active status of a user.
+// @Description  Moves a user to another status (pending_verification, active, suspended, banned or deleted) with a reason. A suspension may end automatically at expires_at. Sessions of a user who is no longer active are revoked.
 // @Tags         ChangeStatus
 // @Accept       json
 // @Produce      json
 // @Param        status body models.ChangeStatus true "status"
 // @Success      200  {object}  string
 // @Failure      400  {object}  models.Response
+// @Failure      403  {object}  models.Response
+// @Failure      409  {object}  models.Response
 // @Failure      500  {object}  models.Response
 func (h Handler) ChangeStatus(c *gin.Context) {
 	var status models.ChangeStatus
@@ -130,26 +225,81 @@ func (h Handler) ChangeStatus(c *gin.Context) {
 		return
 	}
 
-	userID, err := h.Services.Auth().ChangeStatus(c.Request.Context(), status)
+	if err := uuid.Validate(status.ID); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	authInfo, err := getAuthInfo(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while changing user status", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	userID, err := h.Services.Auth().ChangeStatus(c.Request.Context(), authInfo, status)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while changing user status", statusErrorStatus(err), err.Error())
 		return
 	}
 
 	handleResponseLog(c, h.Log, "User status updated successfully", http.StatusOK, "Status changed for: "+userID)
 }
 
+// GetStatusHistory godoc
+// @Security     ApiKeyAuth
+// @Router       /user/{id}/status-history [GET]
+// @Summary      Get user status history
+// @Description  Lists the status transitions of a user, newest first, with reason and actor.
+// @Tags         ChangeStatus
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "user ID"
+// @Success      200  {object}  models.GetStatusHistoryResponse
+// @Failure      400  {object}  models.Response
+// @Failure      403  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h Handler) GetStatusHistory(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	history, err := h.Services.Auth().GetStatusHistory(c.Request.Context(), id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting user status history", http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	handleResponseLog(c, h.Log, "User status history was successfully gotten", http.StatusOK, history)
+}
+
+// statusErrorStatus maps a status change error to an HTTP status code.
+func statusErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidStatus), errors.Is(err, service.ErrInvalidStatusExpiry):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrStatusTransition), errors.Is(err, storage.ErrStatusChanged):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
 // UserLoginMailPassword godoc
 // @Router       /user/login [POST]
 // @Summary      User login
-// @Description  User login
+// @Description  User login. If two-factor authentication is enabled, mfa_required is set and the mfa_token must be redeemed at /user/login/2fa. If the password has expired, password_expired is set and the password_change_token may only be used at /user/password/change.
 // @Tags         Login
 // @Accept       json
 // @Produce      json
 // @Param        login body models.UserLoginRequest true "login"
 // @Success      201  {object}  models.UserLoginResponse
 // @Failure      400  {object}  models.Response
-// @Failure      404  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      403  {object}  models.Response
+// @Failure      429  {object}  models.Response
+// @Header       429  {integer} Retry-After "seconds until the next attempt is allowed"
 // @Failure      500  {object}  models.Response
 func (h Handler) UserLoginMailPassword(c *gin.Context) {
 	loginReq := models.UserLoginRequest{}
@@ -160,14 +310,9 @@ func (h Handler) UserLoginMailPassword(c *gin.Context) {
 	}
 	fmt.Println("loginReq: ", loginReq)
 
-	if err := check.ValidatePassword(loginReq.Password); err != nil {
-		handleResponseLog(c, h.Log, "error while validating password", http.StatusBadRequest, err.Error())
-		return
-	}
-	
-	loginResp, err := h.Services.Auth().UserLoginMailPassword(c.Request.Context(), loginReq)
+	loginResp, err := h.Services.Auth().UserLoginMailPassword(c.Request.Context(), loginReq, clientInfo(c))
 	if err != nil {
-		handleResponseLog(c, h.Log, "unauthorized", http.StatusUnauthorized, err)
+		handleResponseLog(c, h.Log, "unauthorized", loginErrorStatus(c, err, http.StatusInternalServerError), err.Error())
 		return
 	}
 
@@ -185,6 +330,7 @@ func (h Handler) UserLoginMailPassword(c *gin.Context) {
 // @Success      201  {object}  models.Response
 // @Failure      400  {object}  models.Response
 // @Failure      404  {object}  models.Response
+// @Failure      429  {object}  models.Response
 // @Failure      500  {object}  models.Response
 func (h Handler) UserRegister(c *gin.Context) {
 	loginReq := models.UserMail{}
@@ -202,7 +348,7 @@ func (h Handler) UserRegister(c *gin.Context) {
 
 	err := h.Services.Auth().UserRegister(c.Request.Context(), loginReq)
 	if err != nil {
-		handleResponseLog(c, h.Log, "", http.StatusInternalServerError, err)
+		handleResponseLog(c, h.Log, "", otpErrorStatus(err, http.StatusInternalServerError), err.Error())
 		return
 	}
 
@@ -220,6 +366,7 @@ func (h Handler) UserRegister(c *gin.Context) {
 // @Success      201  {object}  models.UserLoginResponse
 // @Failure      400  {object}  models.Response
 // @Failure      404  {object}  models.Response
+// @Failure      429  {object}  models.Response
 // @Failure      500  {object}  models.Response
 func (h Handler) UserRegisterConfirm(c *gin.Context) {
 	req := models.UserLoginMailOtp{}
@@ -235,14 +382,13 @@ func (h Handler) UserRegisterConfirm(c *gin.Context) {
 		return
 	}
 
-	if err := check.ValidatePassword(req.User.Password); err != nil {
-		handleResponseLog(c, h.Log, "error while validating password", http.StatusBadRequest, err.Error())
+	if !h.checkPassword(c, req.User.Password, req.Mail, req.User.FirstName, req.User.LastName) {
 		return
 	}
 
-	confResp, err := h.Services.Auth().UserRegisterConfirm(c.Request.Context(), req)
+	confResp, err := h.Services.Auth().UserRegisterConfirm(c.Request.Context(), req, clientInfo(c))
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while confirming", http.StatusUnauthorized, err.Error())
+		handleResponseLog(c, h.Log, "error while confirming", otpErrorStatus(err, http.StatusUnauthorized), err.Error())
 		return
 	}
 
@@ -261,6 +407,7 @@ func (h Handler) UserRegisterConfirm(c *gin.Context) {
 // @Success      201  {object}  models.Response
 // @Failure      400  {object}  models.Response
 // @Failure      404  {object}  models.Response
+// @Failure      429  {object}  models.Response
 // @Failure      500  {object}  models.Response
 func (h Handler) UserLoginWithEmail(c *gin.Context) {
 	req := models.UserMail{}
@@ -277,7 +424,7 @@ func (h Handler) UserLoginWithEmail(c *gin.Context) {
 
 	err := h.Services.Auth().UserLoginOtp(c.Request.Context(), req)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while sending otp to mail", http.StatusUnauthorized, err.Error())
+		handleResponseLog(c, h.Log, "error while sending otp to mail", otpErrorStatus(err, http.StatusUnauthorized), err.Error())
 		return
 	}
 
@@ -288,40 +435,237 @@ func (h Handler) UserLoginWithEmail(c *gin.Context) {
 // UserLoginWithOtp godoc
 // @Router       /user/login/otp [POST]
 // @Summary      User logins with otp
-// @Description  User inputs otp and mail
+// @Description  User inputs the otp sent by /user/login/email and mail. If two-factor authentication is enabled, mfa_required is set and the mfa_token must be redeemed at /user/login/2fa.
 // @Tags         Login
 // @Accept       json
 // @Produce      json
-// @Param        login body models.UserLoginMailOtp true "login"
+// @Param        login body models.UserLoginOtpRequest true "login"
 // @Success      201  {object}  models.UserLoginResponse
 // @Failure      400  {object}  models.Response
-// @Failure      404  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      403  {object}  models.Response
+// @Failure      429  {object}  models.Response
 // @Failure      500  {object}  models.Response
 func (h Handler) UserLoginWithOtp(c *gin.Context) {
-	req := models.UserLoginMailOtp{}
+	req := models.UserLoginOtpRequest{}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err)
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
 		return
 	}
-	fmt.Println("req: ", req)
 
 	if _, err := check.ValidateEmail(req.Mail); err != nil {
 		handleResponseLog(c, h.Log, "error while validating email"+req.Mail, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if err := check.ValidatePassword(req.User.Password); err != nil {
-		handleResponseLog(c, h.Log, "error while validating password", http.StatusBadRequest, err.Error())
+	loginResp, err := h.Services.Auth().LoginWithOtp(c.Request.Context(), req, clientInfo(c))
+	if err != nil {
+		if errors.Is(err, service.ErrUserInactive) {
+			handleResponseLog(c, h.Log, "error while logging in with otp", http.StatusForbidden, err.Error())
+			return
+		}
+		handleResponseLog(c, h.Log, "error while logging in with otp", otpErrorStatus(err, http.StatusUnauthorized), err.Error())
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Logged in successfully", http.StatusOK, loginResp)
+}
+
+// UserLoginMagicLink godoc
+// @Router       /user/login/magic-link [POST]
+// @Summary      Send a magic login link
+// @Description  Mails a single-use sign-in link to the user. With bind_browser the link only works in the browser that requested it.
+// @Tags         Login
+// @Accept       json
+// @Produce      json
+// @Param        login body models.MagicLinkRequest true "login"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h Handler) UserLoginMagicLink(c *gin.Context) {
+	req := models.MagicLinkRequest{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err := check.ValidateEmail(req.Mail); err != nil {
+		handleResponseLog(c, h.Log, "error while validating email"+req.Mail, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	nonce := ""
+	if req.BindBrowser {
+		var err error
+		nonce, err = generator.Token(32)
+		if err != nil {
+			handleResponseLog(c, h.Log, "error while generating magic link nonce", http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	err := h.Services.Auth().SendMagicLink(c.Request.Context(), req, nonce)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while sending magic link", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if nonce != "" {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(magicLinkNonceCookie, nonce, int(service.MagicLinkTTL.Seconds()), "/user/login/magic-link", "", c.Request.TLS != nil, true)
+	}
+
+	handleResponseLog(c, h.Log, "Magic link sent successfully", http.StatusOK, "Success")
+}
+
+// UserLoginMagicLinkVerify godoc
+// @Router       /user/login/magic-link/verify [GET]
+// @Summary      Log in with a magic link
+// @Description  Exchanges the token of a link sent by /user/login/magic-link for access and refresh tokens. If the user has two-factor authentication enabled an mfa_token is returned instead.
+// @Tags         Login
+// @Accept       json
+// @Produce      json
+// @Param        token query string true "token"
+// @Success      200  {object}  models.UserLoginResponse
+// @Failure      400  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      403  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h Handler) UserLoginMagicLinkVerify(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		handleResponseLog(c, h.Log, "missing magic link token", http.StatusBadRequest, "token is required")
 		return
 	}
 
-	confResp, err := h.Services.Auth().UserRegisterConfirm(c.Request.Context(), req)
+	nonce, _ := c.Cookie(magicLinkNonceCookie)
+
+	loginResp, err := h.Services.Auth().LoginWithMagicLink(c.Request.Context(), token, nonce, clientInfo(c))
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while confirming", http.StatusUnauthorized, err.Error())
+		switch {
+		case errors.Is(err, service.ErrUserInactive):
+			handleResponseLog(c, h.Log, "error while logging in with magic link", http.StatusForbidden, err.Error())
+		case errors.Is(err, service.ErrInvalidMagicLink):
+			handleResponseLog(c, h.Log, "error while logging in with magic link", http.StatusUnauthorized, err.Error())
+		default:
+			handleResponseLog(c, h.Log, "error while logging in with magic link", http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
-	handleResponseLog(c, h.Log, "Succes", http.StatusOK, confResp)
+	if nonce != "" {
+		c.SetCookie(magicLinkNonceCookie, "", -1, "/user/login/magic-link", "", c.Request.TLS != nil, true)
+	}
+
+	handleResponseLog(c, h.Log, "Logged in successfully", http.StatusOK, loginResp)
+}
 
-}
\ No newline at end of file
+// RefreshToken godoc
+// @Router       /user/token/refresh [POST]
+// @Summary      Refresh tokens
+// @Description  Exchanges a refresh token for a new access/refresh token pair. The old refresh token is invalidated.
+// @Tags         Login
+// @Accept       json
+// @Produce      json
+// @Param        refresh body models.RefreshTokenRequest true "refresh"
+// @Success      200  {object}  models.UserLoginResponse
+// @Failure      400  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      403  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h Handler) RefreshToken(c *gin.Context) {
+	req := models.RefreshTokenRequest{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.RefreshToken == "" {
+		handleResponseLog(c, h.Log, "missing refresh token", http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	resp, err := h.Services.Auth().RefreshToken(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrRefreshTokenReused) {
+			handleResponseLog(c, h.Log, "error while refreshing token", http.StatusUnauthorized, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrUserInactive) {
+			handleResponseLog(c, h.Log, "error while refreshing token", http.StatusForbidden, err.Error())
+			return
+		}
+		handleResponseLog(c, h.Log, "error while refreshing token", http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Token refreshed successfully", http.StatusOK, resp)
+}
+
+// Logout godoc
+// @Security     ApiKeyAuth
+// @Router       /user/logout [POST]
+// @Summary      Logout
+// @Description  Revokes the current access token and its refresh token.
+// @Tags         Login
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h Handler) Logout(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := h.Services.Auth().Logout(c.Request.Context(), authInfo); err != nil {
+		handleResponseLog(c, h.Log, "error while logging out", http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Logged out successfully", http.StatusOK, "Success")
+}
+
+// LogoutAll godoc
+// @Security     ApiKeyAuth
+// @Router       /user/logout-all [POST]
+// @Summary      Logout from all devices
+// @Description  Revokes every session of the current user.
+// @Tags         Login
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h Handler) LogoutAll(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := h.Services.Auth().LogoutAll(c.Request.Context(), authInfo); err != nil {
+		handleResponseLog(c, h.Log, "error while logging out from all sessions", http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Logged out from all sessions successfully", http.StatusOK, "Success")
+}
+
+// JWKS godoc
+// @Router       /.well-known/jwks.json [GET]
+// @Summary      JSON Web Key Set
+// @Description  Public keys for verifying tokens issued by this service, selected by the token "kid" header.
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  jwt.JSONWebKeySet
+func (h Handler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jwt.JWKS())
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"user/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const exportFileName = "user-export.zip"
+
+// ExportMe godoc
+// @Security     ApiKeyAuth
+// @Router       /user/me/export [POST]
+// @Summary      Export my data
+// @Description  Starts building a ZIP of JSON and CSV files with everything stored about the current user. The status of the returned export job can be polled at /user/me/export/{id}, and a download link valid for 24 hours is mailed once it is ready.
+// @Tags         Export
+// @Accept       json
+// @Produce      json
+// @Success      202  {object}  models.ExportJob
+// @Failure      401  {object}  models.Response
+// @Failure      429  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h Handler) ExportMe(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	job, err := h.Services.User().Export(c.Request.Context(), authInfo.UserID)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while exporting user data", http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Export started, a download link will be mailed", http.StatusAccepted, job)
+}
+
+// GetExportJob godoc
+// @Security     ApiKeyAuth
+// @Router       /user/me/export/{id} [GET]
+// @Summary      Get my export status
+// @Description  Returns the status (pending, ready or failed) of an export of the current user.
+// @Tags         Export
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Export ID"
+// @Success      200  {object}  models.ExportJob
+// @Failure      400  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      404  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h Handler) GetExportJob(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	id := c.Param("id")
+
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	job, err := h.Services.User().GetExportJob(c.Request.Context(), authInfo.UserID, id)
+	if err != nil {
+		if errors.Is(err, service.ErrExportNotFound) {
+			handleResponseLog(c, h.Log, "error while getting export", http.StatusNotFound, err.Error())
+			return
+		}
+		handleResponseLog(c, h.Log, "error while getting export", http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Export was successfully gotten", http.StatusOK, job)
+}
+
+// DownloadExport godoc
+// @Router       /user/export/download [GET]
+// @Summary      Download a data export
+// @Description  Downloads an export with the signed, expiring link mailed to the user.
+// @Tags         Export
+// @Produce      application/zip
+// @Param        token query string true "export token"
+// @Success      200  {file}    file
+// @Failure      400  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      409  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h Handler) DownloadExport(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		handleResponseLog(c, h.Log, "missing export token", http.StatusBadRequest, "token is required")
+		return
+	}
+
+	archive, err := h.Services.User().DownloadExport(c.Request.Context(), token)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidExportLink):
+			handleResponseLog(c, h.Log, "error while downloading export", http.StatusUnauthorized, err.Error())
+		case errors.Is(err, service.ErrExportNotReady), errors.Is(err, service.ErrExportUnavailable):
+			handleResponseLog(c, h.Log, "error while downloading export", http.StatusConflict, err.Error())
+		default:
+			handleResponseLog(c, h.Log, "error while downloading export", http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+exportFileName+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/zip", archive)
+}
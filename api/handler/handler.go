@@ -3,12 +3,18 @@ package handler
 import (
 	"errors"
 	"fmt"
+	"math"
+	"net/http"
 	"strconv"
 	"user/api/models"
 	"user/config"
-	"user/pkg/jwt"
+	"user/pkg/check"
+	"user/pkg/lockout"
 	"user/pkg/logger"
+	"user/pkg/otp"
+	"user/pkg/ratelimit"
 	"user/service"
+	"user/storage"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,12 +22,14 @@ import (
 type Handler struct {
 	Services service.IServiceManager
 	Log      logger.ILogger
+	Limiter  ratelimit.Limiter
 }
 
-func NewStrg(services service.IServiceManager, log logger.ILogger) Handler {
+func NewStrg(services service.IServiceManager, log logger.ILogger, limiter ratelimit.Limiter) Handler {
 	return Handler{
 		Services: services,
 		Log:      log,
+		Limiter:  limiter,
 	}
 }
 
@@ -108,24 +116,96 @@ func ParseLimitQueryParam(c *gin.Context) (uint64, error) {
 	return limit, nil
 }
 
-func getAuthInfo(c *gin.Context) (models.AuthInfo, error) {
-	accessToken := c.GetHeader("Authorization")
-	if accessToken == "" {
-		return models.AuthInfo{}, errors.New("unauthorized")
+// maxUserAgentLength is the size of the "Sessions"."user_agent" column.
+const maxUserAgentLength = 255
+
+func clientInfo(c *gin.Context) models.ClientInfo {
+	userAgent := []rune(c.Request.UserAgent())
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	return models.ClientInfo{
+		UserAgent: string(userAgent),
+		IP:        c.ClientIP(),
+	}
+}
+
+// otpErrorStatus maps errors of the otp store to HTTP status codes, falling back to fallback.
+func otpErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, otp.ErrCooldown), errors.Is(err, otp.ErrTooManyAttempts):
+		return http.StatusTooManyRequests
+	case errors.Is(err, otp.ErrInvalidCode), errors.Is(err, otp.ErrExpired):
+		return http.StatusUnauthorized
+	}
+	return fallback
+}
+
+// loginErrorStatus maps password login errors to HTTP status codes, falling back to fallback.
+// Throttled attempts get 429 with a Retry-After header.
+func loginErrorStatus(c *gin.Context, err error, fallback int) int {
+	var throttled *lockout.ThrottledError
+	if errors.As(err, &throttled) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		return http.StatusTooManyRequests
+	}
+	if errors.Is(err, storage.ErrInvalidCredentials) {
+		return http.StatusUnauthorized
+	}
+	if errors.Is(err, service.ErrUserInactive) {
+		return http.StatusForbidden
 	}
+	return fallback
+}
+
+// checkPassword validates password against the password policy and the breach corpus
+// and responds with every violation if it fails. personal holds data the password may not contain.
+func (h Handler) checkPassword(c *gin.Context, password string, personal ...string) bool {
+	violations := check.ValidatePassword(password, personal...)
 
-	m, err := jwt.ExtractClaims(accessToken)
+	breached, err := check.ScreenPassword(password)
 	if err != nil {
-		return models.AuthInfo{}, err
+		h.Log.Error("error while screening password against breach corpus", logger.Error(err))
+	}
+	if breached {
+		violations = append(violations, check.BreachedPassword)
+	}
+
+	if len(violations) == 0 {
+		return true
+	}
+
+	handleResponseLog(c, h.Log, "password does not meet the policy", http.StatusBadRequest, violations)
+	return false
+}
+
+// handlePasswordChangeError responds to a failed password change, reporting a wrong old
+// password, a missing user and reuse of a recent password as client errors and anything
+// else with status.
+func (h Handler) handlePasswordChangeError(c *gin.Context, msg string, err error, status int) {
+	if errors.Is(err, storage.ErrInvalidCredentials) {
+		handleResponseLog(c, h.Log, msg, http.StatusBadRequest, []check.FieldError{{
+			Field:   "old_password",
+			Code:    "mismatch",
+			Message: "old password is incorrect",
+		}})
+		return
+	}
+
+	if errors.Is(err, storage.ErrUserNotFound) {
+		handleResponseLog(c, h.Log, msg, http.StatusNotFound, err.Error())
+		return
 	}
 
-	role := m["user_role"].(string)
-	if !(role == config.USER_ROLE) {
-		return models.AuthInfo{}, errors.New("unauthorized")
+	if errors.Is(err, storage.ErrPasswordReused) {
+		handleResponseLog(c, h.Log, msg, http.StatusBadRequest, []check.FieldError{{
+			Field:   "password",
+			Code:    "reused",
+			Message: err.Error(),
+		}})
+		return
 	}
 
-	return models.AuthInfo{
-		UserID:   m["user_id"].(string),
-		UserRole: role,
-	}, nil
+	handleResponseLog(c, h.Log, msg, status, err.Error())
 }
@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"user/api/models"
+	"user/config"
+	"user/pkg/jwt"
+	"user/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+)
+
+const authInfoKey = "auth_info"
+
+// AuthMiddleware validates the access token from the Authorization header
+// and stores the caller's models.AuthInfo in the gin context.
+func (h Handler) AuthMiddleware(c *gin.Context) {
+	accessToken := c.GetHeader("Authorization")
+	if accessToken == "" {
+		handleResponseLog(c, h.Log, "missing authorization header", http.StatusUnauthorized, "unauthorized")
+		c.Abort()
+		return
+	}
+
+	claims, err := jwt.ExtractClaims(accessToken)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while validating access token", http.StatusUnauthorized, err.Error())
+		c.Abort()
+		return
+	}
+
+	if cast.ToString(claims["token_type"]) != jwt.TokenTypeAccess {
+		handleResponseLog(c, h.Log, "token is not an access token", http.StatusUnauthorized, "unauthorized")
+		c.Abort()
+		return
+	}
+
+	info := models.AuthInfo{
+		UserID:      cast.ToString(claims["user_id"]),
+		UserRole:    cast.ToString(claims["user_role"]),
+		Roles:       cast.ToStringSlice(claims["roles"]),
+		Permissions: cast.ToStringSlice(claims["permissions"]),
+		TokenID:     cast.ToString(claims["jti"]),
+		Family:      cast.ToString(claims["family"]),
+		ExpiresAt:   cast.ToInt64(claims["exp"]),
+	}
+	if info.UserRole != config.USER_ROLE && info.UserRole != config.ADMIN_ROLE {
+		handleResponseLog(c, h.Log, "unknown user role", http.StatusUnauthorized, "unauthorized")
+		c.Abort()
+		return
+	}
+
+	revoked, err := h.Services.Auth().IsTokenRevoked(c.Request.Context(), info)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking token revocation", http.StatusInternalServerError, err.Error())
+		c.Abort()
+		return
+	}
+	if revoked {
+		handleResponseLog(c, h.Log, "token has been revoked", http.StatusUnauthorized, "unauthorized")
+		c.Abort()
+		return
+	}
+
+	c.Set(authInfoKey, info)
+	c.Next()
+}
+
+// PasswordChangeAuth authenticates the change password endpoint. Besides access tokens
+// it accepts the restricted token issued at login for an expired password.
+func (h Handler) PasswordChangeAuth(c *gin.Context) {
+	claims, err := jwt.ExtractClaims(c.GetHeader("Authorization"))
+	if err != nil || cast.ToString(claims["token_type"]) != jwt.TokenTypePasswordChange {
+		h.AuthMiddleware(c)
+		return
+	}
+
+	info := models.AuthInfo{
+		UserID:     cast.ToString(claims["user_id"]),
+		TokenID:    cast.ToString(claims["jti"]),
+		ExpiresAt:  cast.ToInt64(claims["exp"]),
+		Restricted: true,
+	}
+
+	revoked, err := h.Services.Auth().IsTokenRevoked(c.Request.Context(), info)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking token revocation", http.StatusInternalServerError, err.Error())
+		c.Abort()
+		return
+	}
+	if revoked {
+		handleResponseLog(c, h.Log, "token has been revoked", http.StatusUnauthorized, "unauthorized")
+		c.Abort()
+		return
+	}
+
+	c.Set(authInfoKey, info)
+	c.Next()
+}
+
+// RequirePermission aborts with 403 unless the authenticated user holds every given permission.
+// It must run after AuthMiddleware.
+func (h Handler) RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info, err := getAuthInfo(c)
+		if err != nil {
+			handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+			c.Abort()
+			return
+		}
+
+		for _, permission := range permissions {
+			if !info.HasPermission(permission) {
+				handleResponseLog(c, h.Log, "missing permission "+permission, http.StatusForbidden, "forbidden")
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// RequireSelfOrPermission lets the request through when the ":id" path parameter
+// is the authenticated user's own id, or when the user holds the given permission.
+// It must run after AuthMiddleware.
+func (h Handler) RequireSelfOrPermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info, err := getAuthInfo(c)
+		if err != nil {
+			handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+			c.Abort()
+			return
+		}
+
+		if c.Param("id") != info.UserID && !info.HasPermission(permission) {
+			handleResponseLog(c, h.Log, "not allowed to access another user", http.StatusForbidden, "forbidden")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RateLimitKey picks the subject a rate limit policy counts requests of.
+// An empty key falls back to the client IP.
+type RateLimitKey func(c *gin.Context) string
+
+// ByIP counts requests per client IP.
+func ByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// ByUser counts requests per authenticated user. It must run after AuthMiddleware.
+func ByUser(c *gin.Context) string {
+	info, err := getAuthInfo(c)
+	if err != nil {
+		return ""
+	}
+	return "user:" + info.UserID
+}
+
+// ByBodyField counts requests per value of a top-level JSON body field, e.g. "mail".
+// The body is restored for the handler.
+func ByBodyField(field string) RateLimitKey {
+	return func(c *gin.Context) string {
+		if c.Request.Body == nil {
+			return ""
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return ""
+		}
+
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return ""
+		}
+
+		value := strings.ToLower(strings.TrimSpace(cast.ToString(fields[field])))
+		if value == "" {
+			return ""
+		}
+		return field + ":" + value
+	}
+}
+
+// RateLimit rejects requests over policy with 429, counting them per subject picked by key,
+// and reports the limit in X-RateLimit-* headers.
+func (h Handler) RateLimit(policy ratelimit.Policy, key RateLimitKey) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject := key(c)
+		if subject == "" {
+			subject = ByIP(c)
+		}
+
+		result := h.Limiter.Allow(c.Request.Context(), policy, subject)
+		reset := strconv.Itoa(int(math.Ceil(result.Reset.Seconds())))
+
+		c.Header("X-RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+		c.Header("X-RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+		c.Header("X-RateLimit-Reset", reset)
+
+		if !result.Allowed {
+			c.Header("Retry-After", reset)
+			handleResponseLog(c, h.Log, "rate limit "+policy.Name+" exceeded", http.StatusTooManyRequests, "too many requests, try again later")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func getAuthInfo(c *gin.Context) (models.AuthInfo, error) {
+	value, ok := c.Get(authInfoKey)
+	if !ok {
+		return models.AuthInfo{}, errors.New("unauthorized")
+	}
+
+	info, ok := value.(models.AuthInfo)
+	if !ok {
+		return models.AuthInfo{}, errors.New("unauthorized")
+	}
+
+	return info, nil
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"user/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetSessions godoc
+// @Security     ApiKeyAuth
+// @Router       /user/me/sessions [GET]
+// @Summary      Get my sessions
+// @Description  Lists the active sessions (devices) of the current user.
+// @Tags         Session
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.GetSessionsResponse
+// @Failure      401  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h Handler) GetSessions(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	sessions, err := h.Services.Session().GetAll(c.Request.Context(), authInfo)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting sessions", http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Sessions were successfully gotten", http.StatusOK, sessions)
+}
+
+// DeleteSession godoc
+// @Security     ApiKeyAuth
+// @Router       /user/me/sessions/{id} [DELETE]
+// @Summary      Delete a session
+// @Description  Revokes one of the current user's sessions by its id.
+// @Tags         Session
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Session ID"
+// @Success      200  {object}  string
+// @Failure      400  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      404  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h Handler) DeleteSession(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating session id", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.Services.Session().Delete(c.Request.Context(), authInfo, id)
+	if err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			handleResponseLog(c, h.Log, "error while deleting session", http.StatusNotFound, err.Error())
+			return
+		}
+		handleResponseLog(c, h.Log, "error while deleting session", http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Session was successfully deleted", http.StatusOK, id)
+}
@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"user/api/models"
+	"user/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EnrollTotp godoc
+// @Security     ApiKeyAuth
+// @Router       /user/me/2fa/totp [POST]
+// @Summary      Enroll TOTP
+// @Description  Creates a new TOTP secret and returns its otpauth:// URI and a QR code PNG as a data URI. It is enabled after confirmation.
+// @Tags         2FA
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.TotpEnrollResponse
+// @Failure      401  {object}  models.Response
+// @Failure      409  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h Handler) EnrollTotp(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	resp, err := h.Services.Totp().Enroll(c.Request.Context(), authInfo)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while enrolling totp", mfaErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Totp enrolled successfully", http.StatusOK, resp)
+}
+
+// ConfirmTotp godoc
+// @Security     ApiKeyAuth
+// @Router       /user/me/2fa/totp/confirm [POST]
+// @Summary      Confirm TOTP
+// @Description  Enables TOTP after checking a code from the authenticator app and returns one-time recovery codes. They are shown only once.
+// @Tags         2FA
+// @Accept       json
+// @Produce      json
+// @Param        code body models.TotpCode true "code"
+// @Success      200  {object}  models.RecoveryCodesResponse
+// @Failure      400  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      409  {object}  models.Response
+// @Failure      429  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h Handler) ConfirmTotp(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	req := models.TotpCode{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp, err := h.Services.Totp().Confirm(c.Request.Context(), authInfo, req.Code)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while confirming totp", mfaErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Totp enabled successfully", http.StatusOK, resp)
+}
+
+// DisableTotp godoc
+// @Security     ApiKeyAuth
+// @Router       /user/me/2fa/totp [DELETE]
+// @Summary      Disable TOTP
+// @Description  Disables TOTP. A current code from the authenticator app is required.
+// @Tags         2FA
+// @Accept       json
+// @Produce      json
+// @Param        code body models.TotpCode true "code"
+// @Success      200  {object}  string
+// @Failure      400  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      404  {object}  models.Response
+// @Failure      429  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h Handler) DisableTotp(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	req := models.TotpCode{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.Services.Totp().Disable(c.Request.Context(), authInfo, req.Code)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while disabling totp", mfaErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Totp disabled successfully", http.StatusOK, "Success")
+}
+
+// GetRecoveryCodesCount godoc
+// @Security     ApiKeyAuth
+// @Router       /user/me/2fa/recovery-codes [GET]
+// @Summary      Count recovery codes
+// @Description  Returns how many unused recovery codes are left.
+// @Tags         2FA
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.RecoveryCodesCount
+// @Failure      401  {object}  models.Response
+// @Failure      404  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h Handler) GetRecoveryCodesCount(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	resp, err := h.Services.Totp().RecoveryCodesCount(c.Request.Context(), authInfo)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while counting recovery codes", mfaErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Got recovery codes count successfully", http.StatusOK, resp)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Security     ApiKeyAuth
+// @Router       /user/me/2fa/recovery-codes [POST]
+// @Summary      Regenerate recovery codes
+// @Description  Invalidates all recovery codes and returns new ones. A current code from the authenticator app is required.
+// @Tags         2FA
+// @Accept       json
+// @Produce      json
+// @Param        code body models.TotpCode true "code"
+// @Success      200  {object}  models.RecoveryCodesResponse
+// @Failure      400  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      404  {object}  models.Response
+// @Failure      429  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	req := models.TotpCode{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp, err := h.Services.Totp().RegenerateRecoveryCodes(c.Request.Context(), authInfo, req.Code)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while regenerating recovery codes", mfaErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Recovery codes regenerated successfully", http.StatusOK, resp)
+}
+
+// UserLoginMfa godoc
+// @Router       /user/login/2fa [POST]
+// @Summary      Complete login with a second factor
+// @Description  Exchanges the mfa_token returned by /user/login and a TOTP code, or a recovery code, for access and refresh tokens. If the password has expired, password_expired is set and only a password_change_token is returned.
+// @Tags         Login
+// @Accept       json
+// @Produce      json
+// @Param        login body models.UserLoginMfaRequest true "login"
+// @Success      200  {object}  models.UserLoginResponse
+// @Failure      400  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      403  {object}  models.Response
+// @Failure      429  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h Handler) UserLoginMfa(c *gin.Context) {
+	req := models.UserLoginMfaRequest{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	loginResp, err := h.Services.Auth().LoginWithMfa(c.Request.Context(), req, clientInfo(c))
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while logging in with second factor", mfaErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	handleResponseLog(c, h.Log, "Logged in successfully", http.StatusOK, loginResp)
+}
+
+// mfaErrorStatus maps two-factor errors of the service layer to HTTP status codes, falling back to fallback.
+func mfaErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, service.ErrTotpAlreadyEnabled):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrTotpNotEnrolled):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidTotpCode), errors.Is(err, service.ErrInvalidMfaToken), errors.Is(err, service.ErrInvalidRecoveryCode):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrTooManyMfaAttempts), errors.Is(err, service.ErrTooManyTotpCodes):
+		return http.StatusTooManyRequests
+	case errors.Is(err, service.ErrUserInactive):
+		return http.StatusForbidden
+	}
+	return fallback
+}
@@ -1,12 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"user/api/models"
 	"user/pkg/check"
 	"user/pkg/password"
+	"user/storage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -42,8 +44,7 @@ func (h Handler) CreateUser(c *gin.Context) {
 		return
 	}
 
-	if err := check.ValidatePassword(user.Password); err != nil {
-		handleResponseLog(c, h.Log, "error while validating password", http.StatusBadRequest, err.Error())
+	if !h.checkPassword(c, user.Password, user.Mail, user.FirstName, user.LastName) {
 		return
 	}
 
@@ -75,6 +76,7 @@ func (h Handler) CreateUser(c *gin.Context) {
 // @Param		user body models.UpdateUser true "user"
 // @Success		200  {object}  string
 // @Failure		400  {object}  models.Response
+// @Failure		403  {object}  models.Response
 // @Failure		404  {object}  models.Response
 // @Failure		500  {object}  models.Response
 func (h Handler) UpdateUser(c *gin.Context) {
@@ -122,6 +124,7 @@ func (h Handler) UpdateUser(c *gin.Context) {
 // @Param		id path string true "user"
 // @Success		200  {object}  models.User
 // @Failure		400  {object}  models.Response
+// @Failure		403  {object}  models.Response
 // @Failure		404  {object}  models.Response
 // @Failure		500  {object}  models.Response
 func (h Handler) GetUserByID(c *gin.Context) {
@@ -154,6 +157,7 @@ func (h Handler) GetUserByID(c *gin.Context) {
 // @Param 			limit query uint64 false "limit"
 // @Success 		200 {object} models.GetAllUsersResponse
 // @Failure 		400 {object} models.Response
+// @Failure 		403 {object} models.Response
 // @Failure 		500 {object} models.Response
 func (h Handler) GetAllUsers(c *gin.Context) {
 	var (
@@ -190,13 +194,14 @@ func (h Handler) GetAllUsers(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Router		/user/{id} [DELETE]
 // @Summary		delete a user by its id
-// @Description This api deletes a user by its id and returns success message.
+// @Description This api soft deletes a user by its id and returns success message. The user can be restored by an admin until the retention window ends.
 // @Tags		user
 // @Accept		json
 // @Produce		json
 // @Param		id path string true "user ID"
 // @Success		200  {object}  nil
 // @Failure		400  {object}  models.Response
+// @Failure		403  {object}  models.Response
 // @Failure		404  {object}  models.Response
 // @Failure		500  {object}  models.Response
 func (h Handler) DeleteUser(c *gin.Context) {
@@ -214,11 +219,138 @@ func (h Handler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	err = h.Services.User().Delete(c.Request.Context(), id)
+	authInfo, err := getAuthInfo(c)
 	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	err = h.Services.User().Delete(c.Request.Context(), id, authInfo.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			handleResponseLog(c, h.Log, "user not found", http.StatusNotFound, err.Error())
+			return
+		}
 		handleResponseLog(c, h.Log, "error while deleting user", http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	handleResponseLog(c, h.Log, "User was successfully deleted", http.StatusOK, id)
 }
+
+// RestoreUser godoc
+// @Security ApiKeyAuth
+// @Router		/user/{id}/restore [POST]
+// @Summary		restore a deleted user
+// @Description This api restores a soft deleted user to the status it had before, as long as it was deleted within the retention window and its mail and phone were not taken by another user.
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		id path string true "user ID"
+// @Success		200  {object}  string
+// @Failure		400  {object}  models.Response
+// @Failure		403  {object}  models.Response
+// @Failure		404  {object}  models.Response
+// @Failure		409  {object}  models.Response
+// @Failure		410  {object}  models.Response
+// @Failure		500  {object}  models.Response
+func (h Handler) RestoreUser(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	status, err := h.Services.User().Restore(c.Request.Context(), id, authInfo.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrUserNotFound):
+			handleResponseLog(c, h.Log, "user not found", http.StatusNotFound, err.Error())
+		case errors.Is(err, storage.ErrUserNotDeleted), errors.Is(err, storage.ErrRestoreConflict):
+			handleResponseLog(c, h.Log, "error while restoring user", http.StatusConflict, err.Error())
+		case errors.Is(err, storage.ErrRetentionExpired):
+			handleResponseLog(c, h.Log, "error while restoring user", http.StatusGone, err.Error())
+		default:
+			handleResponseLog(c, h.Log, "error while restoring user", http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	handleResponseLog(c, h.Log, "User was successfully restored", http.StatusOK, "Restored as "+status+": "+id)
+}
+
+// GetMe godoc
+// @Security ApiKeyAuth
+// @Router		/user/me [GET]
+// @Summary		get the current user
+// @Description This api gets the authenticated user's own information.
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Success		200  {object}  models.User
+// @Failure		400  {object}  models.Response
+// @Failure		401  {object}  models.Response
+func (h Handler) GetMe(c *gin.Context) {
+	if !h.useSelfID(c) {
+		return
+	}
+	h.GetUserByID(c)
+}
+
+// UpdateMe godoc
+// @Security ApiKeyAuth
+// @Router		/user/me [PUT]
+// @Summary		update the current user
+// @Description This api updates the authenticated user and returns its id.
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		user body models.UpdateUser true "user"
+// @Success		200  {object}  string
+// @Failure		400  {object}  models.Response
+// @Failure		401  {object}  models.Response
+// @Failure		500  {object}  models.Response
+func (h Handler) UpdateMe(c *gin.Context) {
+	if !h.useSelfID(c) {
+		return
+	}
+	h.UpdateUser(c)
+}
+
+// DeleteMe godoc
+// @Security ApiKeyAuth
+// @Router		/user/me [DELETE]
+// @Summary		delete the current user
+// @Description This api deletes the authenticated user's own account.
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Success		200  {object}  string
+// @Failure		401  {object}  models.Response
+// @Failure		500  {object}  models.Response
+func (h Handler) DeleteMe(c *gin.Context) {
+	if !h.useSelfID(c) {
+		return
+	}
+	h.DeleteUser(c)
+}
+
+// useSelfID sets the ":id" path parameter to the authenticated user's id,
+// so the "/user/me" aliases can reuse the "/user/:id" handlers.
+func (h Handler) useSelfID(c *gin.Context) bool {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err.Error())
+		return false
+	}
+
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: authInfo.UserID})
+	return true
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type UserLoginRequest struct {
 	Mail     string `json:"mail"`
 	Password string `json:"password"`
@@ -8,15 +10,48 @@ type UserLoginRequest struct {
 type UserLoginResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
+	MfaRequired  bool   `json:"mfa_required,omitempty"`
+	MfaToken     string `json:"mfa_token,omitempty"`
+
+	PasswordExpired     bool   `json:"password_expired,omitempty"`
+	PasswordChangeToken string `json:"password_change_token,omitempty"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type AuthUser struct {
+	ID          string   `json:"id"`
+	Mail        string   `json:"mail"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+
+	PasswordChangedAt time.Time `json:"-"`
 }
 
 type AuthInfo struct {
-	UserID   string `json:"user_id"`
-	UserRole string `json:"user_role"`
+	UserID      string   `json:"user_id"`
+	UserRole    string   `json:"user_role"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+	TokenID     string   `json:"token_id"`
+	Family      string   `json:"family"`
+	ExpiresAt   int64    `json:"expires_at"`
+	// Restricted is set for password change tokens, which may only change the password.
+	Restricted bool `json:"restricted"`
+}
+
+func (a AuthInfo) HasPermission(permission string) bool {
+	for _, p := range a.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
 }
 
 type ChangePassword struct {
-	Mail        string `json:"mail"`
 	OldPassword string `json:"old_password"`
 	NewPassword string `json:"new_password"`
 }
@@ -31,8 +66,18 @@ type UserLoginMailOtp struct {
 	User CreateUser `json:"user"`
 }
 
+type UserLoginOtpRequest struct {
+	Mail string `json:"mail"`
+	Otp  string `json:"otp"`
+}
+
 type ForgetPassword struct {
 	Mail        string `json:"mail"`
 	Otp         string `json:"otp"`
 	NewPassword string `json:"new_password"`
 }
+
+type MagicLinkRequest struct {
+	Mail        string `json:"mail"`
+	BindBrowser bool   `json:"bind_browser"`
+}
@@ -0,0 +1,29 @@
+package models
+
+const (
+	ExportStatusPending = "pending"
+	ExportStatusReady   = "ready"
+	ExportStatusFailed  = "failed"
+)
+
+// UserExport is everything stored about a user, as handed out by a data export.
+type UserExport struct {
+	ExportedAt    string          `json:"exported_at"`
+	Profile       User            `json:"profile"`
+	Roles         UserRoles       `json:"roles"`
+	Sessions      []Session       `json:"sessions"`
+	StatusHistory []StatusChange  `json:"status_history"`
+	TwoFactor     TwoFactorExport `json:"two_factor"`
+}
+
+type TwoFactorExport struct {
+	Enabled                bool  `json:"enabled"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+type ExportJob struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	Status    string `json:"status"`
+	CreatedAt string `json:"created_at"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type UserRoles struct {
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
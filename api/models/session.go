@@ -0,0 +1,29 @@
+package models
+
+type ClientInfo struct {
+	UserAgent string `json:"user_agent"`
+	IP        string `json:"ip"`
+}
+
+type CreateSession struct {
+	UserID        string `json:"user_id"`
+	RefreshFamily string `json:"refresh_family"`
+	UserAgent     string `json:"user_agent"`
+	IP            string `json:"ip"`
+}
+
+type Session struct {
+	ID            string `json:"id"`
+	UserID        string `json:"user_id"`
+	RefreshFamily string `json:"-"`
+	UserAgent     string `json:"user_agent"`
+	IP            string `json:"ip"`
+	Current       bool   `json:"current"`
+	CreatedAt     string `json:"created_at"`
+	LastSeenAt    string `json:"last_seen_at"`
+}
+
+type GetSessionsResponse struct {
+	Sessions []Session `json:"sessions"`
+	Count    int64     `json:"count"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+const (
+	StatusPendingVerification = "pending_verification"
+	StatusActive              = "active"
+	StatusSuspended           = "suspended"
+	StatusBanned              = "banned"
+	StatusDeleted             = "deleted"
+)
+
+type UserStatus struct {
+	Status    string     `json:"status"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type StatusChange struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	FromStatus string     `json:"from_status"`
+	ToStatus   string     `json:"to_status"`
+	Reason     string     `json:"reason"`
+	ActorID    string     `json:"actor_id,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type GetStatusHistoryResponse struct {
+	History []StatusChange `json:"history"`
+	Count   int64          `json:"count"`
+}
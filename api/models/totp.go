@@ -0,0 +1,37 @@
+package models
+
+type UserTotp struct {
+	UserID    string `json:"user_id"`
+	Secret    string `json:"-"`
+	Confirmed bool   `json:"confirmed"`
+}
+
+type TotpEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+	QRCode string `json:"qr_code"`
+}
+
+type TotpCode struct {
+	Code string `json:"code"`
+}
+
+type UserLoginMfaRequest struct {
+	MfaToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type RecoveryCode struct {
+	ID       string `json:"id"`
+	UserID   string `json:"user_id"`
+	CodeHash string `json:"-"`
+}
+
+type RecoveryCodesResponse struct {
+	Codes []string `json:"codes"`
+}
+
+type RecoveryCodesCount struct {
+	Remaining int64 `json:"remaining"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type GetUser struct {
 	ID        string `json:"id"`
 	Mail      string `json:"mail"`
@@ -7,7 +9,7 @@ type GetUser struct {
 	LastName  string `json:"last_name"`
 	Phone     string `json:"phone"`
 	Sex       string `json:"sex"`
-	Active    bool   `json:"active"`
+	Status    string `json:"status"`
 }
 
 type User struct {
@@ -15,10 +17,9 @@ type User struct {
 	Mail      string `json:"mail"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
-	Password  string `json:"password"`
 	Phone     string `json:"phone"`
 	Sex       string `json:"sex"`
-	Active    bool   `json:"active"`
+	Status    string `json:"status"`
 	CreatedAt string `json:"created_at,omitempty"`
 	UpdatedAt string `json:"updated_at"`
 }
@@ -52,5 +53,8 @@ type GetAllUsersResponse struct {
 
 type ChangeStatus struct {
 	ID     string `json:"id"`
-	Active bool   `json:"active"`
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+	// ExpiresAt ends a suspension automatically.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
@@ -1,12 +1,14 @@
 package api
 
 import (
-	"errors"
 	"fmt"
-	"net/http"
+	"time"
 	"user/api/handler"
+	"user/config"
 	"user/pkg/logger"
+	"user/pkg/ratelimit"
 	"user/service"
+	"user/storage"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -15,6 +17,20 @@ import (
 	_ "user/api/docs"
 )
 
+var (
+	// mailIPPolicy and mailPolicy guard the routes that send email, per client and per recipient.
+	mailIPPolicy = ratelimit.Policy{Name: "mail_ip", Limit: 20, Window: time.Hour}
+	mailPolicy   = ratelimit.Policy{Name: "mail", Limit: 5, Window: time.Hour}
+	// loginPolicy guards the routes that check a secret, per client.
+	loginPolicy = ratelimit.Policy{Name: "login", Limit: 30, Window: time.Minute}
+	// publicPolicy guards the remaining public routes, per client.
+	publicPolicy = ratelimit.Policy{Name: "public", Limit: 120, Window: time.Minute}
+	// userPolicy guards every authenticated route, per user.
+	userPolicy = ratelimit.Policy{Name: "user", Limit: 300, Window: time.Minute}
+	// exportPolicy guards data exports, which read everything about a user, per user.
+	exportPolicy = ratelimit.Policy{Name: "export", Limit: 5, Window: time.Hour}
+)
+
 // New ...
 // @title           Swagger Example API
 // @version         1.0
@@ -22,47 +38,75 @@ import (
 // @securityDefinitions.apikey ApiKeyAuth
 // @in header
 // @name Authorization
-func New(services service.IServiceManager, log logger.ILogger) *gin.Engine {
-	h := handler.NewStrg(services, log)
+func New(services service.IServiceManager, log logger.ILogger, redis storage.IRedisStorage) *gin.Engine {
+	h := handler.NewStrg(services, log, ratelimit.New(redis))
+
+	mailIPLimit := h.RateLimit(mailIPPolicy, handler.ByIP)
+	mailLimit := h.RateLimit(mailPolicy, handler.ByBodyField("mail"))
+	loginLimit := h.RateLimit(loginPolicy, handler.ByIP)
+	publicLimit := h.RateLimit(publicPolicy, handler.ByIP)
 
 	r := gin.Default()
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	r.GET("/.well-known/jwks.json", publicLimit, h.JWKS)
 
-	r.POST("/user", h.CreateUser)
+	r.POST("/user", publicLimit, h.CreateUser)
 	
 	//2
-	r.POST("/user/register", h.UserRegister)
-	r.POST("/user/register-confirm", h.UserRegisterConfirm)
+	r.POST("/user/register", mailIPLimit, mailLimit, h.UserRegister)
+	r.POST("/user/register-confirm", loginLimit, h.UserRegisterConfirm)
 	//3
-	r.POST("/user/login", h.UserLoginMailPassword)
+	r.POST("/user/login", loginLimit, h.UserLoginMailPassword)
+	r.POST("/user/login/2fa", loginLimit, h.UserLoginMfa)
 	//4
-	r.POST("/user/login/email", h.UserLoginWithEmail)
-	r.POST("/user/login/otp", h.UserLoginWithOtp)
-	//5
-	r.PATCH("/user/password/change", h.ChangePassword)
+	r.POST("/user/login/email", mailIPLimit, mailLimit, h.UserLoginWithEmail)
+	r.POST("/user/login/otp", loginLimit, h.UserLoginWithOtp)
+	r.POST("/user/login/magic-link", mailIPLimit, mailLimit, h.UserLoginMagicLink)
+	r.GET("/user/login/magic-link/verify", loginLimit, h.UserLoginMagicLinkVerify)
+	r.GET("/user/login/unlock", loginLimit, h.UnlockAccount)
+	r.POST("/user/token/refresh", publicLimit, h.RefreshToken)
 	//6
-	r.POST("/user/password", h.ForgetPassword)
-	r.POST("/user/password/reset", h.ForgetPasswordReset)
-	//7
-	r.PATCH("/user/status", h.ChangeStatus)
+	r.POST("/user/password", mailIPLimit, mailLimit, h.ForgetPassword)
+	r.POST("/user/password/reset", loginLimit, h.ForgetPasswordReset)
+	r.GET("/user/password/policy", publicLimit, h.GetPasswordPolicy)
+	r.GET("/user/export/download", loginLimit, h.DownloadExport)
+	//5
+	r.PATCH("/user/password/change", loginLimit, h.PasswordChangeAuth, h.ChangePassword)
 
-	r.Use(authMiddleware)
+	r.Use(h.AuthMiddleware)
+	r.Use(h.RateLimit(userPolicy, handler.ByUser))
 	r.Use(logMiddleware)
 	//1
-	r.PUT("/user/:id", h.UpdateUser)
-	r.GET("/user/:id", h.GetUserByID)
-	r.GET("/user", h.GetAllUsers)
-	r.DELETE("/user/:id", h.DeleteUser)
+	r.PUT("/user/:id", h.RequireSelfOrPermission(config.PERMISSION_USERS_WRITE), h.UpdateUser)
+	r.GET("/user/:id", h.RequireSelfOrPermission(config.PERMISSION_USERS_READ), h.GetUserByID)
+	r.GET("/user", h.RequirePermission(config.PERMISSION_USERS_READ), h.GetAllUsers)
+	r.DELETE("/user/:id", h.RequireSelfOrPermission(config.PERMISSION_USERS_WRITE), h.DeleteUser)
+	r.POST("/user/:id/restore", h.RequirePermission(config.PERMISSION_USERS_WRITE), h.RestoreUser)
 
-	return r
-}
+	r.GET("/user/me", h.GetMe)
+	r.PUT("/user/me", h.UpdateMe)
+	r.DELETE("/user/me", h.DeleteMe)
+	//7
+	r.PATCH("/user/status", h.RequirePermission(config.PERMISSION_USERS_STATUS), h.ChangeStatus)
+	r.POST("/user/:id/unlock", h.RequirePermission(config.PERMISSION_USERS_STATUS), h.UnlockUser)
+	r.GET("/user/:id/status-history", h.RequirePermission(config.PERMISSION_USERS_STATUS), h.GetStatusHistory)
 
-func authMiddleware(c *gin.Context) {
-	auth := c.GetHeader("Authorization")
-	if auth == "" {
-		c.AbortWithError(http.StatusUnauthorized, errors.New("unauthorized"))
-	}
-	c.Next()
+	r.POST("/user/logout", h.Logout)
+	r.POST("/user/logout-all", h.LogoutAll)
+
+	r.GET("/user/me/sessions", h.GetSessions)
+	r.DELETE("/user/me/sessions/:id", h.DeleteSession)
+
+	r.POST("/user/me/2fa/totp", h.EnrollTotp)
+	r.POST("/user/me/2fa/totp/confirm", h.ConfirmTotp)
+	r.DELETE("/user/me/2fa/totp", h.DisableTotp)
+	r.GET("/user/me/2fa/recovery-codes", h.GetRecoveryCodesCount)
+	r.POST("/user/me/2fa/recovery-codes", h.RegenerateRecoveryCodes)
+
+	r.POST("/user/me/export", h.RateLimit(exportPolicy, handler.ByUser), h.ExportMe)
+	r.GET("/user/me/export/:id", h.GetExportJob)
+
+	return r
 }
 
 func logMiddleware(c *gin.Context) {
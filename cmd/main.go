@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"user/api"
 	"user/config"
+	"user/pkg/check"
+	"user/pkg/encrypt"
+	"user/pkg/jwt"
 	"user/pkg/logger"
+	"user/pkg/password"
 	"user/service"
 	"user/storage/postgres"
 	"user/storage/redis"
@@ -18,6 +22,24 @@ func main() {
 
 	log := logger.New(cfg.ServiceName)
 
+	if err := jwt.LoadKeys(cfg); err != nil {
+		fmt.Println("error while loading jwt signing keys, err: ", err)
+		return
+	}
+
+	if err := encrypt.LoadKey(cfg); err != nil {
+		fmt.Println("error while loading encryption key, err: ", err)
+		return
+	}
+
+	if err := password.Configure(cfg); err != nil {
+		fmt.Println("error while configuring password hashing, err: ", err)
+		return
+	}
+
+	check.ConfigurePasswordPolicy(cfg)
+	check.ConfigurePasswordScreener(cfg)
+
 	newRedis := redis.New(cfg)
 
 	store, err := postgres.New(context.Background(), cfg, log, newRedis)
@@ -27,8 +49,10 @@ func main() {
 	}
 	defer store.CloseDB()
 
-	services := service.New(store, log, newRedis)
-	server := api.New(services, log)
+	services := service.New(cfg, store, log, newRedis)
+	go services.User().RunPurge(context.Background())
+
+	server := api.New(services, log, newRedis)
 
 	fmt.Println("programm is running on localhost:8082...")
 	server.Run(":8082")
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/cast"
@@ -20,6 +21,46 @@ type Config struct {
 	RedisPassword string
 
 	ServiceName string
+
+	JWTKeysDir        string
+	JWTSigningKeyID   string
+	JWTRetiredKeys    string
+	JWTKeyGracePeriod time.Duration
+
+	EncryptionKey string
+
+	MagicLinkURL string
+	UnlockURL    string
+	ExportURL    string
+
+	PasswordHashAlgorithm     string
+	PasswordBcryptCost        int
+	PasswordArgon2Memory      uint32
+	PasswordArgon2Iterations  uint32
+	PasswordArgon2Parallelism uint8
+	PasswordScryptLogN        int
+
+	PasswordMinLength        int
+	PasswordMaxLength        int
+	PasswordRequireLower     bool
+	PasswordRequireUpper     bool
+	PasswordRequireDigit     bool
+	PasswordRequireSymbol    bool
+	PasswordMaxRepeated      int
+	PasswordDisallowPersonal bool
+	PasswordMinStrength      int
+
+	PasswordBreachDir      string
+	PasswordBreachMinCount int64
+
+	PasswordHistorySize int
+	PasswordMaxAge      time.Duration
+
+	// UserRetention is how long a deleted user can be restored before it is purged.
+	UserRetention     time.Duration
+	UserPurgeInterval time.Duration
+	// UserPurgeMode is "delete" to remove purged users or "anonymise" to keep an anonymous row.
+	UserPurgeMode string
 }
 
 func Load() Config {
@@ -41,6 +82,44 @@ func Load() Config {
 	cfg.RedisPort = cast.ToString(getOrReturnDefault("REDIS_PORT", "6379"))
 	cfg.RedisPassword = cast.ToString(getOrReturnDefault("REDIS_PASSWORD", "password"))
 
+	cfg.JWTKeysDir = cast.ToString(getOrReturnDefault("JWT_KEYS_DIR", ""))
+	cfg.JWTSigningKeyID = cast.ToString(getOrReturnDefault("JWT_SIGNING_KEY_ID", ""))
+	cfg.JWTRetiredKeys = cast.ToString(getOrReturnDefault("JWT_RETIRED_KEYS", ""))
+	cfg.JWTKeyGracePeriod = cast.ToDuration(getOrReturnDefault("JWT_KEY_GRACE_PERIOD", "240h"))
+
+	cfg.EncryptionKey = cast.ToString(getOrReturnDefault("ENCRYPTION_KEY", ""))
+
+	cfg.MagicLinkURL = cast.ToString(getOrReturnDefault("MAGIC_LINK_URL", "http://localhost:8082/user/login/magic-link/verify"))
+	cfg.UnlockURL = cast.ToString(getOrReturnDefault("UNLOCK_URL", "http://localhost:8082/user/login/unlock"))
+	cfg.ExportURL = cast.ToString(getOrReturnDefault("EXPORT_URL", "http://localhost:8082/user/export/download"))
+
+	cfg.PasswordHashAlgorithm = cast.ToString(getOrReturnDefault("PASSWORD_HASH_ALGORITHM", "bcrypt"))
+	cfg.PasswordBcryptCost = cast.ToInt(getOrReturnDefault("PASSWORD_BCRYPT_COST", 10))
+	cfg.PasswordArgon2Memory = cast.ToUint32(getOrReturnDefault("PASSWORD_ARGON2_MEMORY", 64*1024))
+	cfg.PasswordArgon2Iterations = cast.ToUint32(getOrReturnDefault("PASSWORD_ARGON2_ITERATIONS", 3))
+	cfg.PasswordArgon2Parallelism = cast.ToUint8(getOrReturnDefault("PASSWORD_ARGON2_PARALLELISM", 2))
+	cfg.PasswordScryptLogN = cast.ToInt(getOrReturnDefault("PASSWORD_SCRYPT_LN", 17))
+
+	cfg.PasswordMinLength = cast.ToInt(getOrReturnDefault("PASSWORD_MIN_LENGTH", 8))
+	cfg.PasswordMaxLength = cast.ToInt(getOrReturnDefault("PASSWORD_MAX_LENGTH", 64))
+	cfg.PasswordRequireLower = cast.ToBool(getOrReturnDefault("PASSWORD_REQUIRE_LOWER", true))
+	cfg.PasswordRequireUpper = cast.ToBool(getOrReturnDefault("PASSWORD_REQUIRE_UPPER", true))
+	cfg.PasswordRequireDigit = cast.ToBool(getOrReturnDefault("PASSWORD_REQUIRE_DIGIT", true))
+	cfg.PasswordRequireSymbol = cast.ToBool(getOrReturnDefault("PASSWORD_REQUIRE_SYMBOL", true))
+	cfg.PasswordMaxRepeated = cast.ToInt(getOrReturnDefault("PASSWORD_MAX_REPEATED", 3))
+	cfg.PasswordDisallowPersonal = cast.ToBool(getOrReturnDefault("PASSWORD_DISALLOW_PERSONAL_INFO", true))
+	cfg.PasswordMinStrength = cast.ToInt(getOrReturnDefault("PASSWORD_MIN_STRENGTH", 2))
+
+	cfg.PasswordBreachDir = cast.ToString(getOrReturnDefault("PASSWORD_BREACH_DIR", ""))
+	cfg.PasswordBreachMinCount = cast.ToInt64(getOrReturnDefault("PASSWORD_BREACH_MIN_COUNT", 1))
+
+	cfg.PasswordHistorySize = cast.ToInt(getOrReturnDefault("PASSWORD_HISTORY_SIZE", 5))
+	cfg.PasswordMaxAge = cast.ToDuration(getOrReturnDefault("PASSWORD_MAX_AGE", "0"))
+
+	cfg.UserRetention = cast.ToDuration(getOrReturnDefault("USER_RETENTION", "720h"))
+	cfg.UserPurgeInterval = cast.ToDuration(getOrReturnDefault("USER_PURGE_INTERVAL", "1h"))
+	cfg.UserPurgeMode = cast.ToString(getOrReturnDefault("USER_PURGE_MODE", "anonymise"))
+
 	return cfg
 }
 
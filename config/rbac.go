@@ -0,0 +1,9 @@
+package config
+
+const (
+	ADMIN_ROLE = "admin"
+
+	PERMISSION_USERS_READ   = "users:read"
+	PERMISSION_USERS_WRITE  = "users:write"
+	PERMISSION_USERS_STATUS = "users:status"
+)
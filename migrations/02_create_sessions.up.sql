@@ -0,0 +1,11 @@
+CREATE TABLE "Sessions" (
+  "id" uuid PRIMARY KEY,
+  "user_id" uuid NOT NULL REFERENCES "Users"("id") ON DELETE CASCADE,
+  "refresh_family" uuid UNIQUE NOT NULL,
+  "user_agent" VARCHAR(255),
+  "ip" VARCHAR(45),
+  "created_at" TIMESTAMP,
+  "last_seen_at" TIMESTAMP
+);
+
+CREATE INDEX "sessions_user_id_idx" ON "Sessions"("user_id");
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "Sessions";
\ No newline at end of file
@@ -0,0 +1,18 @@
+CREATE TABLE "Roles" (
+  "id" SERIAL PRIMARY KEY,
+  "name" VARCHAR(50) UNIQUE NOT NULL,
+  "permissions" TEXT[] NOT NULL DEFAULT '{}'
+);
+
+CREATE TABLE "UserRoles" (
+  "user_id" uuid NOT NULL REFERENCES "Users"("id") ON DELETE CASCADE,
+  "role_id" INT NOT NULL REFERENCES "Roles"("id") ON DELETE CASCADE,
+  PRIMARY KEY ("user_id", "role_id")
+);
+
+INSERT INTO "Roles" ("name", "permissions") VALUES
+  ('admin', '{users:read,users:write,users:status}'),
+  ('user', '{}');
+
+INSERT INTO "UserRoles" ("user_id", "role_id")
+SELECT u."id", r."id" FROM "Users" u CROSS JOIN "Roles" r WHERE r."name" = 'user';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "UserRoles";
+DROP TABLE IF EXISTS "Roles";
\ No newline at end of file
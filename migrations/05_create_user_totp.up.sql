@@ -0,0 +1,6 @@
+CREATE TABLE "UserTotp" (
+  "user_id" uuid PRIMARY KEY REFERENCES "Users"("id") ON DELETE CASCADE,
+  "secret" TEXT NOT NULL,
+  "confirmed_at" TIMESTAMP,
+  "created_at" TIMESTAMP
+);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "UserTotp";
\ No newline at end of file
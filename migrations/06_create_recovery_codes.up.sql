@@ -0,0 +1,9 @@
+CREATE TABLE "RecoveryCodes" (
+  "id" uuid PRIMARY KEY,
+  "user_id" uuid NOT NULL REFERENCES "Users"("id") ON DELETE CASCADE,
+  "code_hash" VARCHAR(255) NOT NULL,
+  "used_at" TIMESTAMP,
+  "created_at" TIMESTAMP
+);
+
+CREATE INDEX "recovery_codes_user_id_idx" ON "RecoveryCodes"("user_id");
\ No newline at end of file
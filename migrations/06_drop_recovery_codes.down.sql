@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "RecoveryCodes";
\ No newline at end of file
@@ -0,0 +1,12 @@
+ALTER TABLE "Users" ADD COLUMN "password_changed_at" TIMESTAMP;
+
+UPDATE "Users" SET "password_changed_at" = COALESCE("updated_at", "created_at", CURRENT_TIMESTAMP);
+
+CREATE TABLE "PasswordHistory" (
+  "id" uuid PRIMARY KEY,
+  "user_id" uuid NOT NULL REFERENCES "Users"("id") ON DELETE CASCADE,
+  "password" VARCHAR(255) NOT NULL,
+  "created_at" TIMESTAMP
+);
+
+CREATE INDEX "password_history_user_id_idx" ON "PasswordHistory"("user_id", "created_at");
\ No newline at end of file
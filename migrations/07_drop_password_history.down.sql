@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "PasswordHistory";
+
+ALTER TABLE "Users" DROP COLUMN IF EXISTS "password_changed_at";
\ No newline at end of file
@@ -0,0 +1,20 @@
+ALTER TABLE "Users" ADD COLUMN "status" VARCHAR(30) NOT NULL DEFAULT 'active';
+ALTER TABLE "Users" ADD COLUMN "status_reason" VARCHAR(255);
+ALTER TABLE "Users" ADD COLUMN "status_expires_at" TIMESTAMP;
+
+UPDATE "Users" SET "status" = 'suspended' WHERE "active" = false;
+
+ALTER TABLE "Users" DROP COLUMN "active";
+
+CREATE TABLE "UserStatusHistory" (
+  "id" uuid PRIMARY KEY,
+  "user_id" uuid NOT NULL REFERENCES "Users"("id") ON DELETE CASCADE,
+  "from_status" VARCHAR(30) NOT NULL,
+  "to_status" VARCHAR(30) NOT NULL,
+  "reason" VARCHAR(255),
+  "actor_id" uuid,
+  "expires_at" TIMESTAMP,
+  "created_at" TIMESTAMP
+);
+
+CREATE INDEX "user_status_history_user_id_idx" ON "UserStatusHistory"("user_id", "created_at");
\ No newline at end of file
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS "UserStatusHistory";
+
+ALTER TABLE "Users" ADD COLUMN "active" BOOLEAN NOT NULL DEFAULT true;
+
+UPDATE "Users" SET "active" = false WHERE "status" <> 'active';
+
+ALTER TABLE "Users" DROP COLUMN IF EXISTS "status_expires_at";
+ALTER TABLE "Users" DROP COLUMN IF EXISTS "status_reason";
+ALTER TABLE "Users" DROP COLUMN IF EXISTS "status";
\ No newline at end of file
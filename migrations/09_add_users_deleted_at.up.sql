@@ -0,0 +1,13 @@
+ALTER TABLE "Users" ADD COLUMN "deleted_at" TIMESTAMP;
+ALTER TABLE "Users" ADD COLUMN "anonymised_at" TIMESTAMP;
+
+UPDATE "Users" SET "deleted_at" = COALESCE("updated_at", CURRENT_TIMESTAMP) WHERE "status" = 'deleted';
+
+CREATE INDEX "users_deleted_at_idx" ON "Users"("deleted_at") WHERE "deleted_at" IS NOT NULL;
+
+-- a deleted user keeps its mail and phone until it is purged, but they are free to be used again
+ALTER TABLE "Users" DROP CONSTRAINT IF EXISTS "Users_mail_key";
+ALTER TABLE "Users" DROP CONSTRAINT IF EXISTS "Users_phone_key";
+
+CREATE UNIQUE INDEX "users_mail_key" ON "Users"("mail") WHERE "deleted_at" IS NULL;
+CREATE UNIQUE INDEX "users_phone_key" ON "Users"("phone") WHERE "deleted_at" IS NULL;
\ No newline at end of file
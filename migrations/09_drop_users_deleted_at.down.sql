@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS "users_phone_key";
+DROP INDEX IF EXISTS "users_mail_key";
+
+ALTER TABLE "Users" ADD CONSTRAINT "Users_mail_key" UNIQUE ("mail");
+ALTER TABLE "Users" ADD CONSTRAINT "Users_phone_key" UNIQUE ("phone");
+
+DROP INDEX IF EXISTS "users_deleted_at_idx";
+
+ALTER TABLE "Users" DROP COLUMN IF EXISTS "anonymised_at";
+ALTER TABLE "Users" DROP COLUMN IF EXISTS "deleted_at";
\ No newline at end of file
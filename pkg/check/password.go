@@ -0,0 +1,186 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	"user/config"
+)
+
+// FieldError is a single validation failure of a request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicy describes the rules new passwords must follow.
+// Zero values switch a rule off.
+type PasswordPolicy struct {
+	MinLength            int  `json:"min_length"`
+	MaxLength            int  `json:"max_length"`
+	RequireLower         bool `json:"require_lower"`
+	RequireUpper         bool `json:"require_upper"`
+	RequireDigit         bool `json:"require_digit"`
+	RequireSymbol        bool `json:"require_symbol"`
+	MaxRepeated          int  `json:"max_repeated"`
+	DisallowPersonalInfo bool `json:"disallow_personal_info"`
+	MinStrength          int  `json:"min_strength"`
+}
+
+var passwordPolicy = PasswordPolicy{
+	MinLength:            8,
+	MaxLength:            64,
+	RequireLower:         true,
+	RequireUpper:         true,
+	RequireDigit:         true,
+	RequireSymbol:        true,
+	MaxRepeated:          3,
+	DisallowPersonalInfo: true,
+	MinStrength:          2,
+}
+
+// ConfigurePasswordPolicy sets the policy ValidatePassword enforces from cfg.
+func ConfigurePasswordPolicy(cfg config.Config) {
+	passwordPolicy = PasswordPolicy{
+		MinLength:            cfg.PasswordMinLength,
+		MaxLength:            cfg.PasswordMaxLength,
+		RequireLower:         cfg.PasswordRequireLower,
+		RequireUpper:         cfg.PasswordRequireUpper,
+		RequireDigit:         cfg.PasswordRequireDigit,
+		RequireSymbol:        cfg.PasswordRequireSymbol,
+		MaxRepeated:          cfg.PasswordMaxRepeated,
+		DisallowPersonalInfo: cfg.PasswordDisallowPersonal,
+		MinStrength:          cfg.PasswordMinStrength,
+	}
+}
+
+// CurrentPasswordPolicy returns the configured policy.
+func CurrentPasswordPolicy() PasswordPolicy {
+	return passwordPolicy
+}
+
+// ValidatePassword checks password against the configured policy and returns every violation.
+// personal holds the user's mail, names etc., which the password may not contain.
+func ValidatePassword(password string, personal ...string) []FieldError {
+	return passwordPolicy.Validate(password, personal...)
+}
+
+// Validate checks password against the policy and returns every violation.
+func (p PasswordPolicy) Validate(password string, personal ...string) []FieldError {
+	var (
+		violations []FieldError
+		length     = utf8.RuneCountInString(password)
+	)
+
+	add := func(code, format string, args ...interface{}) {
+		violations = append(violations, FieldError{
+			Field:   "password",
+			Code:    code,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if p.MinLength > 0 && length < p.MinLength {
+		add("too_short", "password must be at least %d characters", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		add("too_long", "password must be at most %d characters", p.MaxLength)
+	}
+
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	if p.RequireLower && !hasLower {
+		add("missing_lowercase", "password must contain at least one lowercase letter")
+	}
+	if p.RequireUpper && !hasUpper {
+		add("missing_uppercase", "password must contain at least one uppercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		add("missing_digit", "password must contain at least one digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		add("missing_symbol", "password must contain at least one special character")
+	}
+
+	if p.MaxRepeated > 0 && longestRepeat(password) > p.MaxRepeated {
+		add("repeated_characters", "password must not repeat a character more than %d times in a row", p.MaxRepeated)
+	}
+
+	inputs := personalInputs(personal)
+	if p.DisallowPersonalInfo {
+		lower := strings.ToLower(password)
+		for _, input := range inputs {
+			if strings.Contains(lower, input) {
+				add("contains_personal_info", "password must not contain your mail or name")
+				break
+			}
+		}
+	}
+
+	if p.MinStrength > 0 && PasswordStrength(password, inputs...) < p.MinStrength {
+		add("too_weak", "password is too easy to guess, use a longer or less predictable one")
+	}
+
+	return violations
+}
+
+func longestRepeat(s string) int {
+	var (
+		longest, current int
+		prev             rune = -1
+	)
+
+	for _, r := range s {
+		if r == prev {
+			current++
+		} else {
+			current = 1
+		}
+		if current > longest {
+			longest = current
+		}
+		prev = r
+	}
+
+	return longest
+}
+
+// personalInputs lowercases the given values and splits mail addresses into their parts,
+// keeping those long enough to be meaningful.
+func personalInputs(values []string) []string {
+	var inputs []string
+
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+
+		local, _, isMail := strings.Cut(value, "@")
+		if isMail {
+			value = local
+		}
+
+		parts := strings.FieldsFunc(value, func(r rune) bool {
+			return r == '.' || r == '_' || r == '-' || r == '+' || unicode.IsSpace(r)
+		})
+		for _, part := range append(parts, value) {
+			if utf8.RuneCountInString(part) >= 3 {
+				inputs = append(inputs, part)
+			}
+		}
+	}
+
+	return inputs
+}
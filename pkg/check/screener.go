@@ -0,0 +1,118 @@
+package check
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"user/config"
+)
+
+// BreachedPassword is the violation reported for a password found in the breach corpus.
+var BreachedPassword = FieldError{
+	Field:   "password",
+	Code:    "breached",
+	Message: "this password has appeared in a data breach, choose a different one",
+}
+
+const (
+	hashPrefixLength = 5
+	hashSuffixLength = 35
+)
+
+// PasswordScreener checks passwords against a local copy of the Have I Been Pwned
+// corpus laid out like its range API: one file per upper-case 5 character SHA-1
+// prefix, named "<PREFIX>" or "<PREFIX>.txt", with sorted "<SUFFIX>:<COUNT>" lines.
+// Only the range file of the checked password is read, so nothing is loaded up
+// front and no network access is needed.
+type PasswordScreener struct {
+	dir      string
+	minCount int64
+}
+
+// NewPasswordScreener screens against the corpus in dir, treating passwords seen at
+// least minCount times as breached. An empty dir disables screening.
+func NewPasswordScreener(dir string, minCount int64) PasswordScreener {
+	if minCount < 1 {
+		minCount = 1
+	}
+
+	return PasswordScreener{
+		dir:      dir,
+		minCount: minCount,
+	}
+}
+
+var passwordScreener = NewPasswordScreener("", 1)
+
+// ConfigurePasswordScreener sets the corpus ScreenPassword uses from cfg.
+func ConfigurePasswordScreener(cfg config.Config) {
+	passwordScreener = NewPasswordScreener(cfg.PasswordBreachDir, cfg.PasswordBreachMinCount)
+}
+
+// ScreenPassword reports whether password is in the configured breach corpus.
+func ScreenPassword(password string) (bool, error) {
+	return passwordScreener.Breached(password)
+}
+
+// Breached reports whether password appears in the corpus at least minCount times.
+func (s PasswordScreener) Breached(password string) (bool, error) {
+	if s.dir == "" {
+		return false, nil
+	}
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:hashPrefixLength], hash[hashPrefixLength:]
+
+	lines, err := s.readRange(prefix)
+	if err != nil {
+		return false, err
+	}
+
+	i := sort.Search(len(lines), func(i int) bool {
+		return string(lineSuffix(lines[i])) >= suffix
+	})
+	if i == len(lines) || string(lineSuffix(lines[i])) != suffix {
+		return false, nil
+	}
+
+	_, count, _ := bytes.Cut(lines[i], []byte(":"))
+	seen, err := strconv.ParseInt(string(bytes.TrimSpace(count)), 10, 64)
+	if err != nil {
+		// A line without a count still lists a breached password.
+		return true, nil
+	}
+
+	return seen >= s.minCount, nil
+}
+
+// readRange returns the lines of the range file for prefix, or none if the corpus has no such file.
+func (s PasswordScreener) readRange(prefix string) ([][]byte, error) {
+	for _, name := range []string{prefix, prefix + ".txt"} {
+		data, err := os.ReadFile(filepath.Join(s.dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return bytes.Split(bytes.TrimSpace(data), []byte("\n")), nil
+	}
+
+	return nil, nil
+}
+
+func lineSuffix(line []byte) []byte {
+	line = bytes.TrimSpace(line)
+	if len(line) > hashSuffixLength {
+		line = line[:hashSuffixLength]
+	}
+	return bytes.ToUpper(line)
+}
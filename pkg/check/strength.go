@@ -0,0 +1,196 @@
+package check
+
+import (
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// commonPasswords are frequent passwords and password words, after undoing leetspeak.
+var commonPasswords = []string{
+	"password", "qwerty", "letmein", "welcome", "admin", "login", "dragon", "monkey",
+	"football", "baseball", "iloveyou", "master", "sunshine", "princess", "shadow",
+	"superman", "trustno", "starwars", "whatever", "freedom", "hello", "secret",
+	"summer", "winter", "spring", "autumn", "computer", "michael", "jessica",
+	"charlie", "access", "mustang", "batman", "soccer", "hockey", "killer", "pepper",
+	"ginger", "cookie", "flower", "hunter", "ranger", "buster", "thomas", "robert",
+	"jordan", "liverpool", "chelsea", "arsenal", "changeme", "default", "user",
+	"test", "pass", "love", "god",
+}
+
+var keyboardRows = []string{
+	"qwertyuiop", "asdfghjkl", "zxcvbnm", "1234567890",
+	"poiuytrewq", "lkjhgfdsa", "mnbvcxz", "0987654321",
+}
+
+var leet = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s", "!", "i")
+
+// PasswordStrength estimates how hard password is to guess on zxcvbn's 0-4 scale.
+// Like zxcvbn it splits the password into the cheapest patterns an attacker would
+// try (common words, the user's own data, keyboard walks, years, sequences and repeats)
+// and scores the resulting number of guesses: below 10^3 is 0, 10^6 is 1,
+// 10^8 is 2, 10^10 is 3, anything above is 4.
+func PasswordStrength(password string, userInputs ...string) int {
+	var (
+		runes       = []rune(strings.ToLower(password))
+		normalized  = []rune(leet.Replace(strings.ToLower(password)))
+		cardinality = math.Log10(float64(charsetSize(password)))
+		log10       float64
+	)
+
+	if len(normalized) != len(runes) {
+		normalized = runes
+	}
+
+	for i := 0; i < len(runes); {
+		length, guesses := longestPattern(runes, normalized, i, userInputs, cardinality)
+		log10 += guesses
+		i += length
+	}
+
+	switch {
+	case log10 < 3:
+		return 0
+	case log10 < 6:
+		return 1
+	case log10 < 8:
+		return 2
+	case log10 < 10:
+		return 3
+	}
+	return 4
+}
+
+// longestPattern returns the length of the cheapest pattern starting at i and the
+// log10 of the guesses it costs. A lone character costs the full cardinality.
+func longestPattern(runes, normalized []rune, i int, userInputs []string, cardinality float64) (int, float64) {
+	rest := string(normalized[i:])
+	bestLength, bestGuesses := 1, cardinality
+
+	consider := func(length int, guesses float64) {
+		if length > bestLength || (length == bestLength && guesses < bestGuesses) {
+			bestLength, bestGuesses = length, guesses
+		}
+	}
+
+	for _, input := range userInputs {
+		if strings.HasPrefix(string(runes[i:]), input) || strings.HasPrefix(rest, input) {
+			consider(utf8.RuneCountInString(input), 1)
+		}
+	}
+
+	for _, word := range commonPasswords {
+		if strings.HasPrefix(rest, word) {
+			consider(len(word), math.Log10(float64(len(commonPasswords))))
+		}
+	}
+
+	for _, row := range keyboardRows {
+		if length := commonPrefix(string(runes[i:]), row); length >= 3 {
+			consider(length, math.Log10(float64(len(keyboardRows)*length)))
+		}
+		if start := strings.IndexRune(row, runes[i]); start > 0 {
+			if length := commonPrefix(string(runes[i:]), row[start:]); length >= 3 {
+				consider(length, math.Log10(float64(len(row)*length)))
+			}
+		}
+	}
+
+	if isYear(runes[i:]) {
+		consider(4, math.Log10(yearSpace))
+	}
+
+	if length := runLength(runes, i, 0); length >= 3 {
+		consider(length, cardinality+math.Log10(float64(length)))
+	}
+	for _, delta := range []rune{1, -1} {
+		if length := runLength(runes, i, delta); length >= 3 {
+			consider(length, math.Log10(float64(sequenceBase(runes[i])*length)))
+		}
+	}
+
+	return bestLength, bestGuesses
+}
+
+// yearSpace is the number of recent years an attacker would try.
+const yearSpace = 120
+
+func isYear(runes []rune) bool {
+	if len(runes) < 4 {
+		return false
+	}
+	for _, r := range runes[:4] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	prefix := string(runes[:2])
+	return prefix == "19" || prefix == "20"
+}
+
+// runLength returns how many runes from i on each differ from the previous one by delta.
+func runLength(runes []rune, i int, delta rune) int {
+	length := 1
+	for j := i + 1; j < len(runes) && runes[j]-runes[j-1] == delta; j++ {
+		length++
+	}
+	return length
+}
+
+func commonPrefix(s, prefix string) int {
+	length := 0
+	for length < len(s) && length < len(prefix) && s[length] == prefix[length] {
+		length++
+	}
+	return length
+}
+
+func sequenceBase(r rune) int {
+	if unicode.IsDigit(r) {
+		return 10
+	}
+	return 26
+}
+
+func charsetSize(password string) int {
+	var lower, upper, digit, symbol, other bool
+
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < utf8.RuneSelf:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	size := 0
+	if lower {
+		size += 26
+	}
+	if upper {
+		size += 26
+	}
+	if digit {
+		size += 10
+	}
+	if symbol {
+		size += 33
+	}
+	if other {
+		size += 100
+	}
+	if size == 0 {
+		size = 1
+	}
+
+	return size
+}
@@ -53,22 +53,3 @@ func ValidatePhone(phone string) (bool, error) {
 
 	return true, nil
 }
-
-func ValidatePassword(password string) error {
-	if len(password) < 8 {
-		return errors.New("password must be at least 8 characters")
-	}
-	if !regexp.MustCompile(`[a-z]`).MatchString(password) {
-		return errors.New("password must contain at least one lowercase letter")
-	}
-	if !regexp.MustCompile(`[A-Z]`).MatchString(password) {
-		return errors.New("password must contain at least one uppercase letter")
-	}
-	if !regexp.MustCompile(`[0-9]`).MatchString(password) {
-		return errors.New("password must contain at least one digit")
-	}
-	if !regexp.MustCompile(`[^a-zA-Z0-9\s]`).MatchString(password) {
-		return errors.New("password must contain at least one special character")
-	}
-	return nil
-}
@@ -0,0 +1,73 @@
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"user/config"
+)
+
+var (
+	ErrKeyNotConfigured = errors.New("encryption key is not configured")
+	ErrInvalidKey       = errors.New("encryption key must be 32 base64 encoded bytes")
+	ErrInvalidData      = errors.New("invalid encrypted data")
+)
+
+var aead cipher.AEAD
+
+// LoadKey configures the AES-256-GCM key used to encrypt secrets at rest.
+// An empty key is allowed; Encrypt and Decrypt then fail with ErrKeyNotConfigured.
+func LoadKey(cfg config.Config) error {
+	if cfg.EncryptionKey == "" {
+		return nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(cfg.EncryptionKey)
+	if err != nil || len(key) != 32 {
+		return ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+
+	aead, err = cipher.NewGCM(block)
+	return err
+}
+
+// Encrypt seals plaintext and returns base64(nonce || ciphertext).
+func Encrypt(plaintext string) (string, error) {
+	if aead == nil {
+		return "", ErrKeyNotConfigured
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func Decrypt(encrypted string) (string, error) {
+	if aead == nil {
+		return "", ErrKeyNotConfigured
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", ErrInvalidData
+	}
+
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", ErrInvalidData
+	}
+
+	return string(plaintext), nil
+}
@@ -0,0 +1,83 @@
+package generator
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+)
+
+const (
+	Numeric      = "0123456789"
+	Alphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// Crockford is Crockford's base32 alphabet, which leaves out I, L, O and U
+	// so codes are easy to read aloud and retype.
+	Crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+var (
+	ErrInvalidLength   = errors.New("length must be positive")
+	ErrInvalidAlphabet = errors.New("alphabet must contain between 2 and 256 single-byte characters")
+)
+
+// Code returns a random string of length characters drawn uniformly from alphabet
+// using crypto/rand. Bytes that would bias the result are rejected and redrawn.
+func Code(length int, alphabet string) (string, error) {
+	return codeFrom(rand.Reader, length, alphabet)
+}
+
+// codeFrom is Code with the random bytes read from r.
+func codeFrom(r io.Reader, length int, alphabet string) (string, error) {
+	if length <= 0 {
+		return "", ErrInvalidLength
+	}
+	if len(alphabet) < 2 || len(alphabet) > 256 {
+		return "", ErrInvalidAlphabet
+	}
+
+	// Largest multiple of len(alphabet) that fits in a byte; bytes at or above
+	// it are rejected so every character is equally likely.
+	limit := 256 - 256%len(alphabet)
+
+	code := make([]byte, 0, length)
+	buf := make([]byte, length)
+
+	for len(code) < length {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", err
+		}
+
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+
+			code = append(code, alphabet[int(b)%len(alphabet)])
+			if len(code) == length {
+				break
+			}
+		}
+	}
+
+	return string(code), nil
+}
+
+// NumericCode returns a random code of length decimal digits.
+func NumericCode(length int) (string, error) {
+	return Code(length, Numeric)
+}
+
+// Token returns an opaque URL-safe token encoding size random bytes,
+// suitable for magic links and password reset links.
+func Token(size int) (string, error) {
+	if size <= 0 {
+		return "", ErrInvalidLength
+	}
+
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,124 @@
+package generator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCodeLengthAndAlphabet(t *testing.T) {
+	for _, alphabet := range []string{Numeric, Alphanumeric, Crockford} {
+		for _, length := range []int{1, 6, 10, 64} {
+			for i := 0; i < 100; i++ {
+				code, err := Code(length, alphabet)
+				if err != nil {
+					t.Fatalf("Code(%d, %q): %v", length, alphabet, err)
+				}
+				if len(code) != length {
+					t.Fatalf("Code(%d, %q) = %q, want length %d", length, alphabet, code, length)
+				}
+				for _, r := range code {
+					if !strings.ContainsRune(alphabet, r) {
+						t.Fatalf("Code(%d, %q) = %q contains %q", length, alphabet, code, r)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestNumericCode(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := NumericCode(6)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != 6 || strings.Trim(code, Numeric) != "" {
+			t.Fatalf("NumericCode(6) = %q", code)
+		}
+	}
+}
+
+func TestCodeInvalidArguments(t *testing.T) {
+	if _, err := Code(0, Numeric); err != ErrInvalidLength {
+		t.Errorf("Code(0) error = %v, want %v", err, ErrInvalidLength)
+	}
+	if _, err := Code(6, "a"); err != ErrInvalidAlphabet {
+		t.Errorf("Code with 1 character alphabet error = %v, want %v", err, ErrInvalidAlphabet)
+	}
+	if _, err := Token(0); err != ErrInvalidLength {
+		t.Errorf("Token(0) error = %v, want %v", err, ErrInvalidLength)
+	}
+}
+
+// TestCodeRejectsBiasedBytes checks that bytes at or above the largest multiple of the
+// alphabet size that fits in a byte are skipped, and that the next read fills their place.
+func TestCodeRejectsBiasedBytes(t *testing.T) {
+	// For Numeric the limit is 250, so 250 and 255 are rejected and 249 is the last accepted byte.
+	r := bytes.NewReader([]byte{250, 249, 255, 0, 13, 7})
+
+	code, err := codeFrom(r, 3, Numeric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != "903" {
+		t.Errorf("codeFrom = %q, want %q", code, "903")
+	}
+}
+
+// TestCodeUniform draws one character from every possible byte value and checks that each
+// character of alphabets whose size does not divide 256 is produced equally often, where
+// plain modulo reduction would favour the first characters. Rejected bytes leave the reader
+// empty, so they show up as a read error.
+func TestCodeUniform(t *testing.T) {
+	for _, alphabet := range []string{Numeric, Crockford, Alphanumeric} {
+		counts := make(map[rune]int, len(alphabet))
+		rejected := 0
+
+		for b := 0; b < 256; b++ {
+			code, err := codeFrom(bytes.NewReader([]byte{byte(b)}), 1, alphabet)
+			if err != nil {
+				rejected++
+				continue
+			}
+			counts[rune(code[0])]++
+		}
+
+		if rejected != 256%len(alphabet) {
+			t.Errorf("alphabet %q: %d bytes rejected, want %d", alphabet, rejected, 256%len(alphabet))
+		}
+		for _, r := range alphabet {
+			if counts[r] != 256/len(alphabet) {
+				t.Errorf("alphabet %q: %q drawn %d times, want %d", alphabet, r, counts[r], 256/len(alphabet))
+			}
+		}
+	}
+}
+
+func TestCodeShortRead(t *testing.T) {
+	if _, err := codeFrom(bytes.NewReader([]byte{1, 2}), 3, Numeric); err == nil {
+		t.Error("codeFrom with too few bytes returned no error")
+	}
+}
+
+func TestTokenURLSafeAndUnique(t *testing.T) {
+	const urlSafe = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+
+	seen := make(map[string]bool)
+	for i := 0; i < 10000; i++ {
+		token, err := Token(32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(token) != 43 {
+			t.Fatalf("Token(32) = %q, want length 43", token)
+		}
+		if strings.Trim(token, urlSafe) != "" {
+			t.Fatalf("Token(32) = %q is not URL-safe", token)
+		}
+		if seen[token] {
+			t.Fatalf("Token(32) returned %q twice", token)
+		}
+		seen[token] = true
+	}
+}
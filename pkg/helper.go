@@ -2,7 +2,6 @@ package pkg
 
 import (
 	"database/sql"
-	"math/rand"
 )
 
 func NullStringToString(s sql.NullString) string {
@@ -12,8 +11,3 @@ func NullStringToString(s sql.NullString) string {
 
 	return ""
 }
-
-func GenerateOTP() int {
-
-	return rand.Intn(900000) + 100000
-}
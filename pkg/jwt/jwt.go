@@ -1,45 +1,74 @@
 package jwt
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
-	"user/config"
 
 	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+)
+
+const (
+	Issuer   = "user"
+	Audience = "user-api"
+
+	TokenTypeAccess    = "access"
+	TokenTypeRefresh   = "refresh"
+	TokenTypeMfa       = "mfa"
+	TokenTypeMagicLink = "magic_link"
+
+	TokenTypePasswordChange = "password_change"
+	TokenTypeExport         = "export"
+
+	AccessTokenTTL  = 24 * time.Hour
+	RefreshTokenTTL = 10 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidToken    = errors.New("invalid JWT Token")
+	ErrInvalidIssuer   = errors.New("invalid token issuer")
+	ErrInvalidAudience = errors.New("invalid token audience")
 )
 
 func GenJWT(m map[interface{}]interface{}) (string, string, error) {
 	var (
-		accessToken, refreshToken *jwt.Token
-		claims                    jwt.MapClaims
+		claims  = jwt.MapClaims{}
+		rClaims = jwt.MapClaims{}
 	)
 
-	accessToken = jwt.New(jwt.SigningMethodHS256)
-	refreshToken = jwt.New(jwt.SigningMethodHS256)
-
-	claims = accessToken.Claims.(jwt.MapClaims)
-	rClaims := refreshToken.Claims.(jwt.MapClaims)
-
 	for k, v := range m {
 		claims[k.(string)] = v
 		rClaims[k.(string)] = v
 	}
+	now := time.Now().Unix()
 
-	claims["iss"] = "user"
-	claims["iat"] = time.Now().Unix()
-	claims["exp"] = time.Now().AddDate(0, 0, 1).Unix()
+	claims["iss"] = Issuer
+	claims["sub"] = m["user_id"]
+	claims["aud"] = Audience
+	claims["iat"] = now
+	claims["nbf"] = now
+	claims["exp"] = time.Now().Add(AccessTokenTTL).Unix()
+	claims["token_type"] = TokenTypeAccess
+	claims["jti"] = uuid.New().String()
 
-	rClaims["iss"] = "user"
-	rClaims["iat"] = time.Now().Unix()
-	rClaims["exp"] = time.Now().AddDate(0, 0, 10).Unix()
+	rClaims["iss"] = Issuer
+	rClaims["sub"] = m["user_id"]
+	rClaims["aud"] = Audience
+	rClaims["iat"] = now
+	rClaims["nbf"] = now
+	rClaims["exp"] = time.Now().Add(RefreshTokenTTL).Unix()
+	rClaims["token_type"] = TokenTypeRefresh
+	rClaims["jti"] = uuid.New().String()
 
-	accessTokenString, err := accessToken.SignedString(config.SignedKey)
+	accessTokenString, err := keys.sign(claims)
 	if err != nil {
 		err = fmt.Errorf("access_token generating error: %s", err)
 		return "", "", err
 	}
 
-	refreshTokenString, err := refreshToken.SignedString(config.SignedKey)
+	refreshTokenString, err := keys.sign(rClaims)
 	if err != nil {
 		err = fmt.Errorf("refresh_token generating error: %s", err)
 		return "", "", err
@@ -48,27 +77,65 @@ func GenJWT(m map[interface{}]interface{}) (string, string, error) {
 	return accessTokenString, refreshTokenString, nil
 }
 
+// GenToken issues a single token of tokenType that expires after ttl,
+// e.g. a short-lived MFA challenge.
+func GenToken(m map[interface{}]interface{}, tokenType string, ttl time.Duration) (string, error) {
+	claims := jwt.MapClaims{}
+
+	for k, v := range m {
+		claims[k.(string)] = v
+	}
+	now := time.Now().Unix()
+
+	claims["iss"] = Issuer
+	claims["sub"] = m["user_id"]
+	claims["aud"] = Audience
+	claims["iat"] = now
+	claims["nbf"] = now
+	claims["exp"] = time.Now().Add(ttl).Unix()
+	claims["token_type"] = tokenType
+	claims["jti"] = uuid.New().String()
+
+	tokenString, err := keys.sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("%s token generating error: %s", tokenType, err)
+	}
+
+	return tokenString, nil
+}
+
+// ExtractClaims verifies the token signature against the key named by its
+// "kid" header, its expiry, not-before, issuer and audience, and returns its claims.
+// A leading "Bearer " prefix is accepted.
 func ExtractClaims(tokenStr string) (jwt.MapClaims, error) {
-	var (
-		token *jwt.Token
-		err   error
-	)
-	token, err = jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		return config.SignedKey, nil
-	})
+	tokenStr = TrimBearer(tokenStr)
+
+	token, err := jwt.Parse(tokenStr, keys.verificationKey)
 	if err != nil {
-		token, err = jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			return config.SignedKey, nil
-		})
-		if err != nil {
-			return nil, err
-		}
+		return nil, err
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !(ok && token.Valid) {
-		err = fmt.Errorf("invalid JWT Token")
-		return nil, err
+		return nil, ErrInvalidToken
 	}
+
+	if !claims.VerifyIssuer(Issuer, true) {
+		return nil, ErrInvalidIssuer
+	}
+
+	if !claims.VerifyAudience(Audience, true) {
+		return nil, ErrInvalidAudience
+	}
+
 	return claims, nil
 }
+
+// TrimBearer strips an optional case-insensitive "Bearer " prefix from an Authorization header value.
+func TrimBearer(header string) string {
+	header = strings.TrimSpace(header)
+	if len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return header
+}
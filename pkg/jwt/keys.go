@@ -0,0 +1,301 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"user/config"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// LegacyKeyID identifies the shared HS256 secret (config.SignedKey).
+// Tokens without a "kid" header are verified with it.
+const LegacyKeyID = "legacy-hs256"
+
+var (
+	ErrUnknownKey = errors.New("unknown signing key")
+	ErrKeyExpired = errors.New("signing key is retired")
+)
+
+// Key is a signing/verification key identified by its kid.
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	SignKey   interface{}
+	VerifyKey interface{}
+	RetiredAt time.Time
+}
+
+type keySet struct {
+	signing *Key
+	keys    map[string]*Key
+	grace   time.Duration
+}
+
+var keys = newLegacyKeySet()
+
+func newLegacyKeySet() *keySet {
+	legacy := &Key{
+		ID:        LegacyKeyID,
+		Method:    jwt.SigningMethodHS256,
+		SignKey:   config.SignedKey,
+		VerifyKey: config.SignedKey,
+	}
+
+	return &keySet{
+		signing: legacy,
+		keys:    map[string]*Key{LegacyKeyID: legacy},
+	}
+}
+
+// LoadKeys loads every "<kid>.pem" file from cfg.JWTKeysDir and selects
+// cfg.JWTSigningKeyID for signing. Keys listed in cfg.JWTRetiredKeys as
+// "kid=RFC3339" pairs stop signing and are accepted for verification for
+// cfg.JWTKeyGracePeriod after retirement. Without a keys dir the shared
+// HS256 secret keeps being used.
+func LoadKeys(cfg config.Config) error {
+	set := newLegacyKeySet()
+	set.grace = cfg.JWTKeyGracePeriod
+
+	if cfg.JWTKeysDir != "" {
+		files, err := filepath.Glob(filepath.Join(cfg.JWTKeysDir, "*.pem"))
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+
+			key, err := loadKey(kid, file)
+			if err != nil {
+				return fmt.Errorf("loading key %q: %w", kid, err)
+			}
+			set.keys[kid] = key
+		}
+	}
+
+	for _, pair := range strings.Split(cfg.JWTRetiredKeys, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		kid, retiredAt, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid retired key %q, expected kid=RFC3339", pair)
+		}
+
+		key, ok := set.keys[kid]
+		if !ok {
+			return fmt.Errorf("retired key %q: %w", kid, ErrUnknownKey)
+		}
+
+		retired, err := time.Parse(time.RFC3339, retiredAt)
+		if err != nil {
+			return fmt.Errorf("retired key %q: %w", kid, err)
+		}
+		key.RetiredAt = retired
+	}
+
+	if cfg.JWTSigningKeyID != "" {
+		key, ok := set.keys[cfg.JWTSigningKeyID]
+		if !ok {
+			return fmt.Errorf("signing key %q: %w", cfg.JWTSigningKeyID, ErrUnknownKey)
+		}
+		set.signing = key
+	}
+
+	if set.signing.SignKey == nil {
+		return fmt.Errorf("signing key %q has no private key", set.signing.ID)
+	}
+	if !set.signing.RetiredAt.IsZero() {
+		return fmt.Errorf("signing key %q: %w", set.signing.ID, ErrKeyExpired)
+	}
+
+	keys = set
+	return nil
+}
+
+func loadKey(kid, file string) (*Key, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var (
+		signKey   crypto.Signer
+		verifyKey crypto.PublicKey
+	)
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", parsed)
+		}
+		signKey = signer
+	case "RSA PRIVATE KEY":
+		signKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	case "EC PRIVATE KEY":
+		signKey, err = x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	case "PUBLIC KEY":
+		verifyKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+
+	if signKey != nil {
+		verifyKey = signKey.Public()
+	}
+
+	key := &Key{
+		ID:        kid,
+		VerifyKey: verifyKey,
+	}
+	if signKey != nil {
+		key.SignKey = signKey
+	}
+
+	switch pub := verifyKey.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, errors.New("only P-256 EC keys are supported")
+		}
+		key.Method = jwt.SigningMethodES256
+	case ed25519.PublicKey:
+		key.Method = EdDSA
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", verifyKey)
+	}
+
+	return key, nil
+}
+
+func (s *keySet) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = LegacyKeyID
+	}
+
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	if !key.RetiredAt.IsZero() && time.Now().After(key.RetiredAt.Add(s.grace)) {
+		return nil, ErrKeyExpired
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.VerifyKey, nil
+}
+
+func (s *keySet) sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(s.signing.Method, claims)
+	token.Header["kid"] = s.signing.ID
+
+	return token.SignedString(s.signing.SignKey)
+}
+
+// JSONWebKey is a public key in RFC 7517 format.
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS returns the public keys that can currently verify our tokens.
+// The shared HS256 secret is never published.
+func JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+
+	for _, key := range keys.keys {
+		if !key.RetiredAt.IsZero() && time.Now().After(key.RetiredAt.Add(keys.grace)) {
+			continue
+		}
+
+		jwk := JSONWebKey{
+			Kid: key.ID,
+			Use: "sig",
+			Alg: key.Method.Alg(),
+		}
+
+		switch pub := key.VerifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = encodeBase64(pub.N.Bytes())
+			jwk.E = encodeBase64(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = pub.Curve.Params().Name
+			jwk.X = encodeBase64(pub.X.FillBytes(make([]byte, size)))
+			jwk.Y = encodeBase64(pub.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = encodeBase64(pub)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+
+	return set
+}
+
+func encodeBase64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
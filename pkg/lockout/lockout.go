@@ -0,0 +1,172 @@
+package lockout
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"user/storage"
+)
+
+const (
+	// FailureWindow is how long a failed login is remembered.
+	FailureWindow = 15 * time.Minute
+
+	// AccountFreeAttempts and IPFreeAttempts are the failures allowed before backoff starts.
+	AccountFreeAttempts = 3
+	IPFreeAttempts      = 10
+
+	// BaseDelay doubles with every further failure up to MaxDelay.
+	BaseDelay = time.Second
+	MaxDelay  = 5 * time.Minute
+
+	// MaxFailures failed logins within FailureWindow lock the account for LockoutDuration.
+	MaxFailures     = 10
+	LockoutDuration = 30 * time.Minute
+)
+
+// ThrottledError is returned for login attempts that must wait RetryAfter.
+type ThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *ThrottledError) Error() string {
+	if e.Locked {
+		return "account is temporarily locked after too many failed logins"
+	}
+	return "too many failed logins, try again later"
+}
+
+// Guard counts failed logins per account and per IP in redis and throttles
+// further attempts with exponential backoff, locking the account after MaxFailures.
+type Guard struct {
+	redis storage.IRedisStorage
+}
+
+func New(redis storage.IRedisStorage) Guard {
+	return Guard{
+		redis: redis,
+	}
+}
+
+// Check returns a *ThrottledError if a login for mail from ip may not be attempted yet.
+func (g Guard) Check(ctx context.Context, mail, ip string) error {
+	mail = normalize(mail)
+
+	ttl, err := g.redis.TTL(ctx, lockedKey(mail))
+	if err != nil {
+		return err
+	}
+	if ttl > 0 {
+		return &ThrottledError{RetryAfter: ttl, Locked: true}
+	}
+
+	keys := []string{backoffKey("account", mail)}
+	if ip != "" {
+		keys = append(keys, backoffKey("ip", ip))
+	}
+
+	for _, key := range keys {
+		ttl, err := g.redis.TTL(ctx, key)
+		if err != nil {
+			return err
+		}
+		if ttl > 0 {
+			return &ThrottledError{RetryAfter: ttl}
+		}
+	}
+
+	return nil
+}
+
+// Fail records a failed login for mail from ip. It reports true when this failure locked the account.
+func (g Guard) Fail(ctx context.Context, mail, ip string) (bool, error) {
+	mail = normalize(mail)
+
+	failures, err := g.redis.Incr(ctx, failuresKey("account", mail), FailureWindow)
+	if err != nil {
+		return false, err
+	}
+
+	if ip != "" {
+		ipFailures, err := g.redis.Incr(ctx, failuresKey("ip", ip), FailureWindow)
+		if err != nil {
+			return false, err
+		}
+
+		if delay := backoff(ipFailures, IPFreeAttempts); delay > 0 {
+			if err := g.redis.Set(ctx, backoffKey("ip", ip), 1, delay); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	if failures >= MaxFailures {
+		if err := g.redis.Set(ctx, lockedKey(mail), 1, LockoutDuration); err != nil {
+			return false, err
+		}
+		return true, g.Reset(ctx, mail)
+	}
+
+	if delay := backoff(failures, AccountFreeAttempts); delay > 0 {
+		if err := g.redis.Set(ctx, backoffKey("account", mail), 1, delay); err != nil {
+			return false, err
+		}
+	}
+
+	return false, nil
+}
+
+// Reset forgets the failed logins of the account, e.g. after a successful login.
+// Per-IP counters are kept so one valid account cannot be used to clear them.
+func (g Guard) Reset(ctx context.Context, mail string) error {
+	mail = normalize(mail)
+
+	if err := g.redis.Del(ctx, failuresKey("account", mail)); err != nil {
+		return err
+	}
+
+	return g.redis.Del(ctx, backoffKey("account", mail))
+}
+
+// Unlock lifts a lockout of the account and resets its failed logins.
+func (g Guard) Unlock(ctx context.Context, mail string) error {
+	if err := g.redis.Del(ctx, lockedKey(normalize(mail))); err != nil {
+		return err
+	}
+
+	return g.Reset(ctx, mail)
+}
+
+// backoff returns the delay before the next attempt after the given number of failures.
+func backoff(failures int64, free int64) time.Duration {
+	if failures <= free {
+		return 0
+	}
+
+	exp := float64(failures - free - 1)
+	delay := time.Duration(float64(BaseDelay) * math.Pow(2, exp))
+	if delay <= 0 || delay > MaxDelay {
+		return MaxDelay
+	}
+
+	return delay
+}
+
+func normalize(mail string) string {
+	return strings.ToLower(strings.TrimSpace(mail))
+}
+
+func failuresKey(scope, subject string) string {
+	return fmt.Sprintf("login_failures:%s:%s", scope, subject)
+}
+
+func backoffKey(scope, subject string) string {
+	return fmt.Sprintf("login_backoff:%s:%s", scope, subject)
+}
+
+func lockedKey(mail string) string {
+	return "login_locked:" + mail
+}
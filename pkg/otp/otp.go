@@ -0,0 +1,160 @@
+package otp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+	"user/pkg/generator"
+	"user/storage"
+
+	"github.com/spf13/cast"
+)
+
+const (
+	PurposeRegister      = "register"
+	PurposeLogin         = "login"
+	PurposePasswordReset = "password_reset"
+
+	CodeLength     = 6
+	CodeTTL        = 2 * time.Minute
+	ResendCooldown = time.Minute
+	MaxAttempts    = 5
+)
+
+var (
+	ErrInvalidCode     = errors.New("incorrect otp code")
+	ErrExpired         = errors.New("otp code is expired or was not requested")
+	ErrTooManyAttempts = errors.New("too many incorrect otp attempts, request a new code")
+	ErrCooldown        = errors.New("otp code was sent recently, try again later")
+)
+
+// Store keeps one-time codes in redis, keyed by purpose and subject (e.g. a mail address),
+// so a code issued for one flow can never satisfy another. Subjects are compared
+// case-insensitively and without surrounding whitespace. Codes are stored as an HMAC
+// keyed with a server secret, so reading redis is not enough to recover them, and are
+// deleted after a successful verification or after MaxAttempts failures.
+type Store struct {
+	redis  storage.IRedisStorage
+	secret []byte
+}
+
+func New(redis storage.IRedisStorage, secret []byte) Store {
+	return Store{
+		redis:  redis,
+		secret: secret,
+	}
+}
+
+// Issue generates a new code for the purpose and subject, replacing any previous one.
+// It fails with ErrCooldown if a code was issued less than ResendCooldown ago.
+// The cooldown is lifted again if the code cannot be stored.
+func (s Store) Issue(ctx context.Context, purpose, subject string) (string, error) {
+	subject = normalize(subject)
+
+	allowed, err := s.redis.SetNX(ctx, cooldownKey(purpose, subject), 1, ResendCooldown)
+	if err != nil {
+		return "", err
+	}
+	if !allowed {
+		return "", ErrCooldown
+	}
+
+	code, err := s.issue(ctx, purpose, subject)
+	if err != nil {
+		if delErr := s.redis.Del(ctx, cooldownKey(purpose, subject)); delErr != nil {
+			return "", errors.Join(err, delErr)
+		}
+		return "", err
+	}
+
+	return code, nil
+}
+
+func (s Store) issue(ctx context.Context, purpose, subject string) (string, error) {
+	code, err := generator.NumericCode(CodeLength)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.redis.Set(ctx, codeKey(purpose, subject), s.hashCode(purpose, subject, code), CodeTTL); err != nil {
+		return "", err
+	}
+
+	if err := s.redis.Del(ctx, attemptsKey(purpose, subject)); err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// Cancel discards the code issued for the purpose and subject and lifts the resend cooldown,
+// for when the code could not be delivered.
+func (s Store) Cancel(ctx context.Context, purpose, subject string) error {
+	subject = normalize(subject)
+
+	if err := s.redis.Del(ctx, codeKey(purpose, subject)); err != nil {
+		return err
+	}
+
+	return s.redis.Del(ctx, cooldownKey(purpose, subject))
+}
+
+// Verify checks code against the one issued for the purpose and subject and consumes it on success.
+func (s Store) Verify(ctx context.Context, purpose, subject, code string) error {
+	subject = normalize(subject)
+
+	stored, err := s.redis.Get(ctx, codeKey(purpose, subject))
+	if err != nil {
+		return ErrExpired
+	}
+
+	attempts, err := s.redis.Incr(ctx, attemptsKey(purpose, subject), CodeTTL)
+	if err != nil {
+		return err
+	}
+	if attempts > MaxAttempts {
+		if err := s.redis.Del(ctx, codeKey(purpose, subject)); err != nil {
+			return err
+		}
+		return ErrTooManyAttempts
+	}
+
+	expected := []byte(cast.ToString(stored))
+	actual := []byte(s.hashCode(purpose, subject, code))
+	if subtle.ConstantTimeCompare(expected, actual) != 1 {
+		return ErrInvalidCode
+	}
+
+	if err := s.redis.Del(ctx, codeKey(purpose, subject)); err != nil {
+		return err
+	}
+
+	return s.redis.Del(ctx, attemptsKey(purpose, subject))
+}
+
+func (s Store) hashCode(purpose, subject, code string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(purpose + ":" + subject + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func normalize(subject string) string {
+	return strings.ToLower(strings.TrimSpace(subject))
+}
+
+func codeKey(purpose, subject string) string {
+	return "otp:" + purpose + ":" + subject
+}
+
+func attemptsKey(purpose, subject string) string {
+	return "otp_attempts:" + purpose + ":" + subject
+}
+
+func cooldownKey(purpose, subject string) string {
+	return "otp_cooldown:" + purpose + ":" + subject
+}
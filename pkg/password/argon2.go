@@ -0,0 +1,138 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// DefaultArgon2id follows the OWASP recommendation of 64 MiB, 3 passes and 2 lanes.
+var DefaultArgon2id = Argon2id{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Upper bounds on the cost parameters accepted from a stored hash, so a crafted or
+// corrupted hash cannot make a single verification exhaust memory or CPU.
+const (
+	maxArgon2Memory     = 1 << 20 // 1 GiB
+	maxArgon2Iterations = 100
+)
+
+// Argon2id hashes passwords with Argon2id. Memory is in KiB.
+type Argon2id struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (a Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, a.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		a.Memory,
+		a.Iterations,
+		a.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (a Argon2id) Verify(encoded, password string) error {
+	params, err := decodeArgon2("argon2id", encoded)
+	if err != nil {
+		return err
+	}
+
+	key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+	if subtle.ConstantTimeCompare(key, params.key) != 1 {
+		return ErrMismatch
+	}
+
+	return nil
+}
+
+func (a Argon2id) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func (a Argon2id) NeedsRehash(encoded string) bool {
+	params, err := decodeArgon2("argon2id", encoded)
+	if err != nil {
+		return true
+	}
+
+	return params.memory < a.Memory ||
+		params.iterations < a.Iterations ||
+		params.parallelism < a.Parallelism ||
+		uint32(len(params.key)) < a.KeyLength
+}
+
+// decodeArgon2 parses "$<variant>$v=19$m=<KiB>,t=<passes>,p=<lanes>$<salt>$<key>".
+func decodeArgon2(variant, encoded string) (argon2Params, error) {
+	var (
+		params  argon2Params
+		version int
+	)
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != variant {
+		return params, ErrMalformedHash
+	}
+
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, ErrMalformedHash
+	}
+	if version != argon2.Version {
+		return params, ErrIncompatibleHash
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return params, ErrMalformedHash
+	}
+	if !validArgon2Params(params.memory, params.iterations, params.parallelism) {
+		return params, ErrMalformedHash
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, ErrMalformedHash
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(params.key) == 0 {
+		return params, ErrMalformedHash
+	}
+
+	return params, nil
+}
+
+// validArgon2Params reports whether argon2 accepts the parameters without panicking, which
+// needs at least one pass, one lane and 8 KiB of memory per lane, and whether they are within
+// the upper bounds.
+func validArgon2Params(memory, iterations uint32, parallelism uint8) bool {
+	return iterations >= 1 && iterations <= maxArgon2Iterations &&
+		parallelism >= 1 &&
+		memory >= 8*uint32(parallelism) && memory <= maxArgon2Memory
+}
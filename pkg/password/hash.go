@@ -1,16 +1,116 @@
 package password
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"user/config"
 
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrMismatch         = errors.New("password does not match")
+	ErrUnknownAlgorithm = errors.New("unknown password hashing algorithm")
+	ErrMalformedHash    = errors.New("malformed password hash")
+	ErrIncompatibleHash = errors.New("incompatible password hash version")
+)
+
+// Hasher hashes passwords into self-describing strings and verifies them.
+// Argon2id and scrypt hashes use the PHC string format; bcrypt keeps its
+// modular crypt format ("$2a$<cost>$..."), which PHC is modelled on, so
+// hashes stored before this package supported other algorithms stay valid.
+type Hasher interface {
+	// Hash returns the encoded hash of password with a random salt.
+	Hash(password string) (string, error)
+	// Verify returns ErrMismatch if password does not match encoded.
+	Verify(encoded, password string) error
+	// Matches reports whether encoded was produced by this algorithm.
+	Matches(encoded string) bool
+	// NeedsRehash reports whether encoded uses weaker parameters than the hasher.
+	NeedsRehash(encoded string) bool
+}
+
+var (
+	hashers = []Hasher{
+		DefaultBcrypt,
+		DefaultArgon2id,
+		DefaultScrypt,
+	}
+	preferred Hasher = DefaultBcrypt
+)
+
+// Configure selects the algorithm and cost new hashes are created with from
+// cfg.PasswordHashAlgorithm ("bcrypt", "argon2id" or "scrypt"). Hashes of
+// every algorithm keep being verified.
+func Configure(cfg config.Config) error {
+	switch strings.ToLower(cfg.PasswordHashAlgorithm) {
+	case "", "bcrypt":
+		cost := cfg.PasswordBcryptCost
+		if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost %d out of range", cost)
+		}
+		preferred = Bcrypt{Cost: cost}
+	case "argon2id":
+		argon := DefaultArgon2id
+		argon.Memory = cfg.PasswordArgon2Memory
+		argon.Iterations = cfg.PasswordArgon2Iterations
+		argon.Parallelism = cfg.PasswordArgon2Parallelism
+		if !validArgon2Params(argon.Memory, argon.Iterations, argon.Parallelism) {
+			return fmt.Errorf("argon2id parameters m=%d,t=%d,p=%d out of range", argon.Memory, argon.Iterations, argon.Parallelism)
+		}
+		preferred = argon
+	case "scrypt":
+		scr := DefaultScrypt
+		scr.LogN = cfg.PasswordScryptLogN
+		if scr.LogN < 10 || scr.LogN > 30 {
+			return fmt.Errorf("scrypt ln %d out of range", scr.LogN)
+		}
+		preferred = scr
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownAlgorithm, cfg.PasswordHashAlgorithm)
+	}
+
+	return nil
+}
+
+// HashPassword hashes password with the preferred algorithm.
 func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return preferred.Hash(password)
+}
+
+// CompareHashAndPassword verifies password against a hash of any supported algorithm.
+func CompareHashAndPassword(hashedPassword, password string) error {
+	hasher, err := hasherFor(hashedPassword)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	return string(hashedPassword), nil
+	return hasher.Verify(hashedPassword, password)
 }
 
-func CompareHashAndPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+// NeedsRehash reports whether hashedPassword should be replaced by a hash with
+// the preferred algorithm and cost, e.g. after the password was verified at login.
+func NeedsRehash(hashedPassword string) bool {
+	if !preferred.Matches(hashedPassword) {
+		return true
+	}
+
+	return preferred.NeedsRehash(hashedPassword)
+}
+
+func hasherFor(encoded string) (Hasher, error) {
+	for _, hasher := range hashers {
+		if hasher.Matches(encoded) {
+			return hasher, nil
+		}
+	}
+
+	for _, hasher := range legacyHashers {
+		if hasher.Matches(encoded) {
+			return hasher, nil
+		}
+	}
+
+	return nil, ErrUnknownAlgorithm
 }
@@ -0,0 +1,143 @@
+package password
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// ErrLegacyAlgorithm is returned when hashing with an algorithm that is only
+// kept to verify passwords imported from other systems.
+var ErrLegacyAlgorithm = errors.New("legacy password hashing algorithm can only verify")
+
+// legacyHashers verify hashes imported from other systems. NeedsRehash is
+// always true for them, so they are replaced at the first successful login.
+var legacyHashers = []Hasher{
+	DjangoPBKDF2{},
+	Argon2i{},
+	SaltedDigest{Name: "sha1", New: sha1.New},
+	SaltedDigest{Name: "md5", New: md5.New},
+}
+
+// maxPBKDF2Iterations is well above Django's current default of 1,000,000 but keeps a
+// crafted hash from tying up a request for minutes.
+const maxPBKDF2Iterations = 5_000_000
+
+// DjangoPBKDF2 verifies Django's "pbkdf2_sha256$<iterations>$<salt>$<base64 key>" hashes.
+type DjangoPBKDF2 struct{}
+
+func (DjangoPBKDF2) Hash(string) (string, error) {
+	return "", ErrLegacyAlgorithm
+}
+
+func (DjangoPBKDF2) Verify(encoded, password string) error {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2_sha256" {
+		return ErrMalformedHash
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 || iterations > maxPBKDF2Iterations {
+		return ErrMalformedHash
+	}
+
+	expected, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil || len(expected) == 0 {
+		return ErrMalformedHash
+	}
+
+	key := pbkdf2.Key([]byte(password), []byte(parts[2]), iterations, len(expected), sha256.New)
+	if subtle.ConstantTimeCompare(key, expected) != 1 {
+		return ErrMismatch
+	}
+
+	return nil
+}
+
+func (DjangoPBKDF2) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "pbkdf2_sha256$")
+}
+
+func (DjangoPBKDF2) NeedsRehash(string) bool {
+	return true
+}
+
+// Argon2i verifies "$argon2i$v=19$m=<KiB>,t=<passes>,p=<lanes>$<salt>$<key>" hashes, as
+// created by PHP's PASSWORD_ARGON2I.
+type Argon2i struct{}
+
+func (Argon2i) Hash(string) (string, error) {
+	return "", ErrLegacyAlgorithm
+}
+
+func (Argon2i) Verify(encoded, password string) error {
+	params, err := decodeArgon2("argon2i", encoded)
+	if err != nil {
+		return err
+	}
+
+	key := argon2.Key([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+	if subtle.ConstantTimeCompare(key, params.key) != 1 {
+		return ErrMismatch
+	}
+
+	return nil
+}
+
+func (Argon2i) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2i$")
+}
+
+func (Argon2i) NeedsRehash(string) bool {
+	return true
+}
+
+// SaltedDigest verifies Django's legacy "<name>$<salt>$<hex digest>" hashes, where the
+// digest is taken over salt followed by the password.
+type SaltedDigest struct {
+	Name string
+	New  func() hash.Hash
+}
+
+func (s SaltedDigest) Hash(string) (string, error) {
+	return "", ErrLegacyAlgorithm
+}
+
+func (s SaltedDigest) Verify(encoded, password string) error {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 3 || parts[0] != s.Name {
+		return ErrMalformedHash
+	}
+
+	expected, err := hex.DecodeString(strings.ToLower(parts[2]))
+	if err != nil {
+		return ErrMalformedHash
+	}
+
+	digest := s.New()
+	digest.Write([]byte(parts[1] + password))
+
+	if subtle.ConstantTimeCompare(digest.Sum(nil), expected) != 1 {
+		return ErrMismatch
+	}
+
+	return nil
+}
+
+func (s SaltedDigest) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, s.Name+"$")
+}
+
+func (s SaltedDigest) NeedsRehash(string) bool {
+	return true
+}
@@ -0,0 +1,118 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// DefaultScrypt uses N=2^17, r=8, p=1 as recommended by OWASP.
+var DefaultScrypt = Scrypt{
+	LogN:       17,
+	R:          8,
+	P:          1,
+	SaltLength: 16,
+	KeyLength:  32,
+}
+
+// Scrypt hashes passwords with scrypt, N = 2^LogN.
+type Scrypt struct {
+	LogN       int
+	R          int
+	P          int
+	SaltLength int
+	KeyLength  int
+}
+
+type scryptParams struct {
+	logN int
+	r    int
+	p    int
+	salt []byte
+	key  []byte
+}
+
+func (s Scrypt) Hash(password string) (string, error) {
+	salt := make([]byte, s.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key, err := scrypt.Key([]byte(password), salt, 1<<s.LogN, s.R, s.P, s.KeyLength)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s$%s",
+		s.LogN,
+		s.R,
+		s.P,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (s Scrypt) Verify(encoded, password string) error {
+	params, err := decodeScrypt(encoded)
+	if err != nil {
+		return err
+	}
+
+	key, err := scrypt.Key([]byte(password), params.salt, 1<<params.logN, params.r, params.p, len(params.key))
+	if err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare(key, params.key) != 1 {
+		return ErrMismatch
+	}
+
+	return nil
+}
+
+func (s Scrypt) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "$scrypt$")
+}
+
+func (s Scrypt) NeedsRehash(encoded string) bool {
+	params, err := decodeScrypt(encoded)
+	if err != nil {
+		return true
+	}
+
+	return params.logN < s.LogN ||
+		params.r < s.R ||
+		params.p < s.P ||
+		len(params.key) < s.KeyLength
+}
+
+// decodeScrypt parses "$scrypt$ln=<log2 N>,r=<block size>,p=<parallelism>$<salt>$<key>".
+func decodeScrypt(encoded string) (scryptParams, error) {
+	var params scryptParams
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 || parts[1] != "scrypt" {
+		return params, ErrMalformedHash
+	}
+
+	if _, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &params.logN, &params.r, &params.p); err != nil {
+		return params, ErrMalformedHash
+	}
+	if params.logN < 1 || params.logN > 30 {
+		return params, ErrMalformedHash
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[3]); err != nil {
+		return params, ErrMalformedHash
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil || len(params.key) == 0 {
+		return params, ErrMalformedHash
+	}
+
+	return params, nil
+}
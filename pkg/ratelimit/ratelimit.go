@@ -0,0 +1,179 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+	"user/storage"
+
+	"github.com/spf13/cast"
+)
+
+// Policy allows Limit requests per Window for each subject (an IP, a user id, a mail...).
+// Name keeps the counters of different policies apart.
+type Policy struct {
+	Name   string
+	Limit  int64
+	Window time.Duration
+}
+
+// Result describes the state of a subject's limit after a request.
+type Result struct {
+	Allowed   bool
+	Limit     int64
+	Remaining int64
+	Reset     time.Duration
+}
+
+// counter is a store of expiring counters.
+type counter interface {
+	incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	get(ctx context.Context, key string) (int64, error)
+}
+
+// Limiter enforces policies with a sliding window counter: the count of the current
+// fixed window plus the count of the previous one, weighted by how much of it still
+// overlaps the sliding window. Counters live in redis so every instance shares them;
+// while redis is unavailable an in-process store is used instead.
+type Limiter struct {
+	redis    counter
+	fallback counter
+}
+
+func New(redis storage.IRedisStorage) Limiter {
+	return Limiter{
+		redis:    redisCounter{redis: redis},
+		fallback: newMemoryCounter(),
+	}
+}
+
+// Allow counts a request of subject under policy and reports whether it is within the limit.
+func (l Limiter) Allow(ctx context.Context, policy Policy, subject string) Result {
+	result, err := allow(ctx, l.redis, policy, subject, time.Now())
+	if err != nil {
+		result, _ = allow(ctx, l.fallback, policy, subject, time.Now())
+	}
+
+	return result
+}
+
+func allow(ctx context.Context, store counter, policy Policy, subject string, now time.Time) (Result, error) {
+	windowStart := now.Truncate(policy.Window)
+
+	current, err := store.incr(ctx, windowKey(policy, subject, windowStart), 2*policy.Window)
+	if err != nil {
+		return Result{}, err
+	}
+
+	previous, err := store.get(ctx, windowKey(policy, subject, windowStart.Add(-policy.Window)))
+	if err != nil {
+		return Result{}, err
+	}
+
+	elapsed := float64(now.Sub(windowStart)) / float64(policy.Window)
+	estimated := int64(math.Ceil(float64(previous)*(1-elapsed))) + current
+
+	remaining := policy.Limit - estimated
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return Result{
+		Allowed:   estimated <= policy.Limit,
+		Limit:     policy.Limit,
+		Remaining: remaining,
+		Reset:     windowStart.Add(policy.Window).Sub(now),
+	}, nil
+}
+
+func windowKey(policy Policy, subject string, windowStart time.Time) string {
+	return fmt.Sprintf("ratelimit:%s:%s:%d", policy.Name, subject, windowStart.Unix())
+}
+
+type redisCounter struct {
+	redis storage.IRedisStorage
+}
+
+func (r redisCounter) incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return r.redis.Incr(ctx, key, ttl)
+}
+
+// get returns 0 for a missing key. It runs right after a successful incr,
+// so an error here is taken as a missing key rather than an outage.
+func (r redisCounter) get(ctx context.Context, key string) (int64, error) {
+	value, err := r.redis.Get(ctx, key)
+	if err != nil {
+		return 0, nil
+	}
+
+	return cast.ToInt64(value), nil
+}
+
+const memorySweepInterval = time.Minute
+
+type memoryEntry struct {
+	count     int64
+	expiresAt time.Time
+}
+
+// memoryCounter is the in-process fallback store. Limits are then enforced per instance.
+type memoryCounter struct {
+	mu        *sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep *time.Time
+}
+
+func newMemoryCounter() memoryCounter {
+	now := time.Now()
+
+	return memoryCounter{
+		mu:        &sync.Mutex{},
+		entries:   map[string]*memoryEntry{},
+		lastSweep: &now,
+	}
+}
+
+func (m memoryCounter) incr(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweep(now)
+
+	entry, ok := m.entries[key]
+	if !ok || now.After(entry.expiresAt) {
+		entry = &memoryEntry{expiresAt: now.Add(ttl)}
+		m.entries[key] = entry
+	}
+	entry.count++
+
+	return entry.count, nil
+}
+
+func (m memoryCounter) get(_ context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return 0, nil
+	}
+
+	return entry.count, nil
+}
+
+// sweep drops expired entries at most once per memorySweepInterval. m.mu must be held.
+func (m memoryCounter) sweep(now time.Time) {
+	if now.Sub(*m.lastSweep) < memorySweepInterval {
+		return
+	}
+
+	for key, entry := range m.entries {
+		if now.After(entry.expiresAt) {
+			delete(m.entries, key)
+		}
+	}
+	*m.lastSweep = now
+}
@@ -2,90 +2,170 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"net/url"
 	"time"
 	"user/api/models"
 	"user/config"
-	"user/pkg"
+	"user/pkg/generator"
 	"user/pkg/jwt"
+	"user/pkg/lockout"
 	"user/pkg/logger"
+	"user/pkg/otp"
 	"user/pkg/password"
 
 	"user/pkg/smtp"
 	"user/storage"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cast"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, session revoked")
+	ErrUserInactive        = errors.New("user account is not active")
+	ErrInvalidMfaToken     = errors.New("invalid or expired mfa token")
+	ErrTooManyMfaAttempts  = errors.New("too many incorrect two-factor codes, log in again")
+	ErrInvalidMagicLink    = errors.New("invalid or expired magic link")
+	ErrInvalidUnlockToken  = errors.New("invalid or expired unlock link")
+)
+
+const (
+	mfaTokenTTL         = 5 * time.Minute
+	mfaTokenMaxAttempts = 5
+
+	passwordChangeTokenTTL = 15 * time.Minute
+
+	// MagicLinkTTL is how long a magic login link stays valid.
+	MagicLinkTTL     = 15 * time.Minute
+	magicLinkPurpose = "login"
 )
 
 type authService struct {
+	cfg     config.Config
 	storage storage.IStorage
 	logger  logger.ILogger
 	redis   storage.IRedisStorage
+	otp     otp.Store
+	lockout lockout.Guard
 }
 
-func NewAuthService(storage storage.IStorage, log logger.ILogger, redis storage.IRedisStorage) authService {
+func NewAuthService(cfg config.Config, storage storage.IStorage, log logger.ILogger, redis storage.IRedisStorage) authService {
 	return authService{
+		cfg:     cfg,
 		storage: storage,
 		logger:  log,
 		redis:   redis,
+		otp:     otp.New(redis, otpSecret(cfg)),
+		lockout: lockout.New(redis),
 	}
 }
 
-func (a authService) ChangePassword(ctx context.Context, pass models.ChangePassword) (string, error) {
-	result, err := a.storage.User().ChangePassword(ctx, pass)
+// otpSecret is the key one-time codes are hashed with: the encryption key,
+// or the token signing secret if no encryption key is configured.
+func otpSecret(cfg config.Config) []byte {
+	if cfg.EncryptionKey != "" {
+		return []byte(cfg.EncryptionKey)
+	}
+	return config.SignedKey
+}
+
+// ChangePassword changes the authenticated user's password and revokes every other session.
+// A restricted password change token is revoked as well.
+func (a authService) ChangePassword(ctx context.Context, info models.AuthInfo, pass models.ChangePassword) (string, error) {
+	result, err := a.storage.User().ChangePassword(ctx, info.UserID, pass)
 	if err != nil {
 		a.logger.Error("failed to change password", logger.Error(err))
 		return "", err
 	}
+
+	if err := a.revokeUserSessions(ctx, info.UserID, info.Family); err != nil {
+		a.logger.Error("failed to revoke sessions after password change", logger.Error(err))
+		return "", err
+	}
+
+	if info.Restricted {
+		if err := a.revokeToken(ctx, info); err != nil {
+			a.logger.Error("failed to revoke password change token", logger.Error(err))
+			return "", err
+		}
+	}
 	return result, nil
 }
 
 func (a authService) ForgetPasswordReset(ctx context.Context, forget models.ForgetPassword) (string, error) {
-	hashedPass, err := password.HashPassword(forget.NewPassword)
+	err := a.otp.Verify(ctx, otp.PurposePasswordReset, forget.Mail, forget.Otp)
 	if err != nil {
-		a.logger.Error("failed to generate user new password", logger.Error(err))
+		a.logger.Error("error while verifying otp code for password reset", logger.Error(err))
 		return "", err
 	}
-	forget.NewPassword = string(hashedPass)
 
 	result, err := a.storage.User().ForgetPassword(ctx, forget)
 	if err != nil {
 		a.logger.Error("failed to reset password", logger.Error(err))
 		return "", err
 	}
-	return result, nil
-}
 
-func (a authService) ChangeStatus(ctx context.Context, status models.ChangeStatus) (string, error) {
-	result, err := a.storage.User().ChangeStatus(ctx, status)
-	if err != nil {
-		a.logger.Error("failed to change user status", logger.Error(err))
+	if err := a.revokeSessionsByMail(ctx, forget.Mail); err != nil {
+		a.logger.Error("failed to revoke sessions after password reset", logger.Error(err))
 		return "", err
 	}
 	return result, nil
 }
 
-func (a authService) UserLoginMailPassword(ctx context.Context, user models.UserLoginRequest) (models.UserLoginResponse, error) {
+func (a authService) UserLoginMailPassword(ctx context.Context, user models.UserLoginRequest, client models.ClientInfo) (models.UserLoginResponse, error) {
+
+	if err := a.lockout.Check(ctx, user.Mail, client.IP); err != nil {
+		a.logger.Error("login attempt throttled", logger.String("mail", user.Mail), logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
 
-	_, err := a.storage.User().LoginByMailAndPassword(ctx, user)
+	authUser, err := a.storage.User().LoginByMailAndPassword(ctx, user)
 	if err != nil {
 		a.logger.Error("error while getting user credentials by login", logger.Error(err))
+		if errors.Is(err, storage.ErrInvalidCredentials) {
+			a.recordLoginFailure(ctx, user.Mail, client.IP)
+		}
 		return models.UserLoginResponse{}, err
 	}
 
-	m := make(map[interface{}]interface{})
+	if err := a.lockout.Reset(ctx, user.Mail); err != nil {
+		a.logger.Error("error while resetting failed logins", logger.Error(err))
+	}
 
-	m["user_role"] = config.USER_ROLE
+	if err := a.checkStatus(ctx, authUser.ID); err != nil {
+		return models.UserLoginResponse{}, err
+	}
 
-	accessToken, refreshToken, err := jwt.GenJWT(m)
+	passwordExpired := a.cfg.PasswordMaxAge > 0 && time.Since(authUser.PasswordChangedAt) > a.cfg.PasswordMaxAge
+
+	userTotp, err := a.storage.Totp().GetByUserID(ctx, authUser.ID)
+	if err != nil {
+		a.logger.Error("error while checking two-factor enrollment", logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
+	if userTotp.Confirmed {
+		return a.mfaChallenge(authUser.ID, passwordExpired)
+	}
+
+	if passwordExpired {
+		return a.passwordChangeChallenge(authUser.ID)
+	}
+
+	roles := models.UserRoles{Roles: authUser.Roles, Permissions: authUser.Permissions}
+
+	resp, err := a.startSession(ctx, userClaims(authUser.ID, roles), client)
 	if err != nil {
 		a.logger.Error("error while generating tokens for user login", logger.Error(err))
 		return models.UserLoginResponse{}, err
 	}
 
-	return models.UserLoginResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	}, nil
+	return resp, nil
 }
 
 func (a authService) UserLoginOtp(ctx context.Context, mail models.UserMail) error {
@@ -96,84 +176,693 @@ func (a authService) UserLoginOtp(ctx context.Context, mail models.UserMail) err
 		return errors.New("gmail address isn't registered")
 	}
 
-	otpCode := pkg.GenerateOTP()
+	return a.sendOtp(ctx, otp.PurposeLogin, mail.Mail, "Your OTP code is: %v, for logging in. Don't give it to anyone")
+}
+
+// LoginWithMfa completes a password login of a user with two-factor authentication
+// by redeeming the challenge token from UserLoginMailPassword together with a TOTP code
+// or, if the authenticator is lost, one of the user's recovery codes.
+// If the password has expired only a password change token is issued.
+func (a authService) LoginWithMfa(ctx context.Context, req models.UserLoginMfaRequest, client models.ClientInfo) (models.UserLoginResponse, error) {
+	claims, err := jwt.ExtractClaims(req.MfaToken)
+	if err != nil || cast.ToString(claims["token_type"]) != jwt.TokenTypeMfa {
+		return models.UserLoginResponse{}, ErrInvalidMfaToken
+	}
 
-	msg := fmt.Sprintf("Your OTP code is: %v, for registering. Don't give it to anyone", otpCode)
+	userID := cast.ToString(claims["user_id"])
+	jti := cast.ToString(claims["jti"])
 
-	err = a.redis.Set(ctx, mail.Mail, otpCode, time.Minute*2)
+	attempts, err := a.redis.Incr(ctx, "mfa_attempts:"+jti, mfaTokenTTL)
 	if err != nil {
-		a.logger.Error("error while setting otpCode to redis User register", logger.Error(err))
-		return err
+		a.logger.Error("error while counting mfa attempts", logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
+	if attempts > mfaTokenMaxAttempts {
+		return models.UserLoginResponse{}, ErrTooManyMfaAttempts
 	}
 
-	err = smtp.SendMail(mail.Mail, msg)
+	userTotp, err := a.storage.Totp().GetByUserID(ctx, userID)
 	if err != nil {
-		a.logger.Error("error while sending otp code to User register", logger.Error(err))
+		a.logger.Error("error while getting two-factor enrollment", logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
+	if !userTotp.Confirmed {
+		return models.UserLoginResponse{}, ErrInvalidMfaToken
+	}
+
+	if err := a.checkStatus(ctx, userID); err != nil {
+		return models.UserLoginResponse{}, err
+	}
+
+	if req.RecoveryCode != "" {
+		if err := a.useRecoveryCode(ctx, userID, req.RecoveryCode); err != nil {
+			return models.UserLoginResponse{}, err
+		}
+	} else if err := verifyTotp(ctx, a.redis, userTotp, req.Code); err != nil {
+		return models.UserLoginResponse{}, err
+	}
+
+	firstUse, err := a.redis.SetNX(ctx, "mfa_used:"+jti, userID, mfaTokenTTL)
+	if err != nil {
+		a.logger.Error("error while marking mfa token as used", logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
+	if !firstUse {
+		return models.UserLoginResponse{}, ErrInvalidMfaToken
+	}
+
+	if cast.ToBool(claims["password_expired"]) {
+		return a.passwordChangeChallenge(userID)
+	}
+
+	roles, err := a.storage.Role().GetByUserID(ctx, userID)
+	if err != nil {
+		a.logger.Error("error while getting roles for mfa login", logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
+
+	resp, err := a.startSession(ctx, userClaims(userID, roles), client)
+	if err != nil {
+		a.logger.Error("error while generating tokens for mfa login", logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
+
+	return resp, nil
+}
+
+// useRecoveryCode consumes a recovery code of the user and tells them by mail how many are left.
+func (a authService) useRecoveryCode(ctx context.Context, userID, code string) error {
+	if err := consumeRecoveryCode(ctx, a.storage, userID, code); err != nil {
+		if !errors.Is(err, ErrInvalidRecoveryCode) {
+			a.logger.Error("error while consuming recovery code", logger.Error(err))
+		}
 		return err
 	}
 
+	user, err := a.storage.User().GetByID(ctx, userID)
+	if err != nil {
+		a.logger.Error("error while getting user for recovery code notice", logger.Error(err))
+		return nil
+	}
+
+	remaining, err := a.storage.RecoveryCode().CountUnused(ctx, userID)
+	if err != nil {
+		a.logger.Error("error while counting recovery codes", logger.Error(err))
+		return nil
+	}
+
+	msg := fmt.Sprintf("A recovery code was just used to sign in to your account. You have %d recovery codes left. If this was not you, change your password and regenerate your recovery codes.", remaining)
+	if err := smtp.SendMail(user.Mail, msg); err != nil {
+		a.logger.Error("error while sending recovery code notice", logger.Error(err))
+	}
+
 	return nil
 }
 
-func (a authService) UserRegister(ctx context.Context, loginRequest models.UserMail) error {
-	_, err := a.storage.User().CheckMailExists(ctx, loginRequest.Mail)
+// passwordChangeChallenge returns a short-lived token for a user whose password has expired.
+// It is only accepted by the change password endpoint.
+func (a authService) passwordChangeChallenge(userID string) (models.UserLoginResponse, error) {
+	m := make(map[interface{}]interface{})
+
+	m["user_id"] = userID
+
+	token, err := jwt.GenToken(m, jwt.TokenTypePasswordChange, passwordChangeTokenTTL)
 	if err != nil {
-		a.logger.Error("error while checking email existence", logger.Error(err))
-		return err
+		a.logger.Error("error while generating password change token", logger.Error(err))
+		return models.UserLoginResponse{}, err
 	}
 
-	fmt.Println(" loginRequest.Login: ", loginRequest.Mail)
-	otpCode := pkg.GenerateOTP()
+	return models.UserLoginResponse{
+		PasswordExpired:     true,
+		PasswordChangeToken: token,
+	}, nil
+}
+
+// mfaChallenge returns a short-lived token that LoginWithMfa exchanges for a session,
+// or for a password change token if passwordExpired is set.
+func (a authService) mfaChallenge(userID string, passwordExpired bool) (models.UserLoginResponse, error) {
+	m := make(map[interface{}]interface{})
 
-	msg := fmt.Sprintf("Your OTP code is: %v, for registering. Don't give it to anyone", otpCode)
+	m["user_id"] = userID
+	if passwordExpired {
+		m["password_expired"] = true
+	}
 
-	err = a.redis.Set(ctx, loginRequest.Mail, otpCode, time.Minute*2)
+	mfaToken, err := jwt.GenToken(m, jwt.TokenTypeMfa, mfaTokenTTL)
 	if err != nil {
-		a.logger.Error("error while setting otpCode to redis user register", logger.Error(err))
-		return err
+		a.logger.Error("error while generating mfa token", logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
+
+	return models.UserLoginResponse{
+		MfaRequired: true,
+		MfaToken:    mfaToken,
+	}, nil
+}
+
+// LoginWithOtp logs an existing, active user in with a code sent by UserLoginOtp.
+// Users with two-factor authentication get an mfa challenge instead of tokens.
+func (a authService) LoginWithOtp(ctx context.Context, req models.UserLoginOtpRequest, client models.ClientInfo) (models.UserLoginResponse, error) {
+	err := a.otp.Verify(ctx, otp.PurposeLogin, req.Mail, req.Otp)
+	if err != nil {
+		a.logger.Error("error while verifying otp code for login", logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
+
+	user, err := a.storage.User().GetByMail(ctx, req.Mail)
+	if err != nil {
+		a.logger.Error("error while getting user by mail for otp login", logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
+
+	if err := a.checkStatus(ctx, user.ID); err != nil {
+		return models.UserLoginResponse{}, err
+	}
+
+	userTotp, err := a.storage.Totp().GetByUserID(ctx, user.ID)
+	if err != nil {
+		a.logger.Error("error while checking two-factor enrollment", logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
+	if userTotp.Confirmed {
+		return a.mfaChallenge(user.ID, false)
+	}
+
+	roles, err := a.storage.Role().GetByUserID(ctx, user.ID)
+	if err != nil {
+		a.logger.Error("error while getting roles for otp login", logger.Error(err))
+		return models.UserLoginResponse{}, err
 	}
 
-	err = smtp.SendMail(loginRequest.Mail, msg)
+	resp, err := a.startSession(ctx, userClaims(user.ID, roles), client)
 	if err != nil {
-		a.logger.Error("error while sending otp code to user register", logger.Error(err))
+		a.logger.Error("error while generating tokens for otp login", logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
+
+	return resp, nil
+}
+
+func (a authService) ForgetPassword(ctx context.Context, mail models.UserMail) error {
+
+	_, err := a.storage.User().CheckMailExists(ctx, mail.Mail)
+	if err != nil {
+		a.logger.Error("gmail address isn't registered", logger.Error(err))
+		return errors.New("gmail address isn't registered")
+	}
+
+	return a.sendOtp(ctx, otp.PurposePasswordReset, mail.Mail, "Your OTP code is: %v, for resetting your password. Don't give it to anyone")
+}
+
+func (a authService) UserRegister(ctx context.Context, loginRequest models.UserMail) error {
+	_, err := a.storage.User().CheckMailExists(ctx, loginRequest.Mail)
+	if err != nil {
+		a.logger.Error("error while checking email existence", logger.Error(err))
 		return err
 	}
 
-	return nil
+	return a.sendOtp(ctx, otp.PurposeRegister, loginRequest.Mail, "Your OTP code is: %v, for registering. Don't give it to anyone")
 }
 
-func (a authService) UserRegisterConfirm(ctx context.Context, req models.UserLoginMailOtp) (models.UserLoginResponse, error) {
+func (a authService) UserRegisterConfirm(ctx context.Context, req models.UserLoginMailOtp, client models.ClientInfo) (models.UserLoginResponse, error) {
 	resp := models.UserLoginResponse{}
 
-	otp, err := a.redis.Get(ctx, req.Mail)
+	err := a.otp.Verify(ctx, otp.PurposeRegister, req.Mail, req.Otp)
 	if err != nil {
-		a.logger.Error("error while getting otp code for customer register confirm", logger.Error(err))
+		a.logger.Error("error while verifying otp code for customer register confirm", logger.Error(err))
 		return resp, err
 	}
 
-	if req.Otp != otp {
-		a.logger.Error("incorrect otp code for customer register confirm", logger.Error(err))
-		return resp, errors.New("incorrect otp code")
+	hashedPass, err := password.HashPassword(req.User.Password)
+	if err != nil {
+		a.logger.Error("error while hashing password for customer register confirm", logger.Error(err))
+		return resp, err
 	}
 
 	req.User.Mail = req.Mail
+	req.User.Password = hashedPass
 	id, err := a.storage.User().Create(ctx, req.User)
 	if err != nil {
 		a.logger.Error("error while creating customer", logger.Error(err))
 		return resp, err
 	}
-	var m = make(map[interface{}]interface{})
-
-	m["user_id"] = id
-	m["user_role"] = config.USER_ROLE
+	roles, err := a.storage.Role().GetByUserID(ctx, id)
+	if err != nil {
+		a.logger.Error("error while getting roles for customer register confirm", logger.Error(err))
+		return resp, err
+	}
 
-	accessToken, refreshToken, err := jwt.GenJWT(m)
+	resp, err = a.startSession(ctx, userClaims(id, roles), client)
 	if err != nil {
 		a.logger.Error("error while generating tokens for customer register confirm", logger.Error(err))
 		return resp, err
 	}
-	resp.AccessToken = accessToken
-	resp.RefreshToken = refreshToken
 
 	return resp, nil
 }
+
+func (a authService) RefreshToken(ctx context.Context, req models.RefreshTokenRequest) (models.UserLoginResponse, error) {
+	claims, err := jwt.ExtractClaims(req.RefreshToken)
+	if err != nil {
+		a.logger.Error("error while extracting refresh token claims", logger.Error(err))
+		return models.UserLoginResponse{}, ErrInvalidRefreshToken
+	}
+
+	family := cast.ToString(claims["family"])
+	if cast.ToString(claims["token_type"]) != jwt.TokenTypeRefresh || family == "" {
+		return models.UserLoginResponse{}, ErrInvalidRefreshToken
+	}
+
+	active, err := a.redis.Exists(ctx, refreshFamilyKey(family))
+	if err != nil {
+		a.logger.Error("error while checking refresh token family", logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
+	if !active {
+		return models.UserLoginResponse{}, ErrInvalidRefreshToken
+	}
+
+	firstUse, err := a.redis.SetNX(ctx, usedRefreshTokenKey(req.RefreshToken), family, jwt.RefreshTokenTTL)
+	if err != nil {
+		a.logger.Error("error while marking refresh token as used", logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
+	if !firstUse {
+		a.logger.Warning("refresh token reuse detected, revoking family", logger.String("family", family))
+		if err := a.redis.Del(ctx, refreshFamilyKey(family)); err != nil {
+			a.logger.Error("error while revoking refresh token family", logger.Error(err))
+			return models.UserLoginResponse{}, err
+		}
+		if err := a.storage.Session().DeleteByFamily(ctx, family); err != nil {
+			a.logger.Error("error while deleting revoked session", logger.Error(err))
+		}
+		return models.UserLoginResponse{}, ErrRefreshTokenReused
+	}
+
+	userID := cast.ToString(claims["user_id"])
+
+	if err := a.checkStatus(ctx, userID); err != nil {
+		return models.UserLoginResponse{}, err
+	}
+
+	roles, err := a.storage.Role().GetByUserID(ctx, userID)
+	if err != nil {
+		a.logger.Error("error while getting roles for refresh", logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
+
+	resp, err := a.generateTokens(ctx, userClaims(userID, roles), family)
+	if err != nil {
+		a.logger.Error("error while generating tokens for refresh", logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
+
+	if err := a.storage.Session().UpdateLastSeen(ctx, family); err != nil {
+		a.logger.Error("error while updating session last seen", logger.Error(err))
+	}
+
+	return resp, nil
+}
+
+// startSession persists a new session for the user in m and issues its first token pair.
+func (a authService) startSession(ctx context.Context, m map[interface{}]interface{}, client models.ClientInfo) (models.UserLoginResponse, error) {
+	family := uuid.New().String()
+
+	_, err := a.storage.Session().Create(ctx, models.CreateSession{
+		UserID:        cast.ToString(m["user_id"]),
+		RefreshFamily: family,
+		UserAgent:     client.UserAgent,
+		IP:            client.IP,
+	})
+	if err != nil {
+		return models.UserLoginResponse{}, err
+	}
+
+	return a.generateTokens(ctx, m, family)
+}
+
+// generateTokens issues an access/refresh pair bound to the given refresh token
+// family and keeps the family alive in redis for the refresh token lifetime.
+func (a authService) generateTokens(ctx context.Context, m map[interface{}]interface{}, family string) (models.UserLoginResponse, error) {
+	m["family"] = family
+
+	accessToken, refreshToken, err := jwt.GenJWT(m)
+	if err != nil {
+		return models.UserLoginResponse{}, err
+	}
+
+	userID := cast.ToString(m["user_id"])
+
+	err = a.redis.Set(ctx, refreshFamilyKey(family), userID, jwt.RefreshTokenTTL)
+	if err != nil {
+		return models.UserLoginResponse{}, err
+	}
+
+	err = a.redis.SAdd(ctx, userFamiliesKey(userID), family, jwt.RefreshTokenTTL)
+	if err != nil {
+		return models.UserLoginResponse{}, err
+	}
+
+	return models.UserLoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// sendOtp issues a code for the purpose and mails it using msgFormat.
+func (a authService) sendOtp(ctx context.Context, purpose, mail, msgFormat string) error {
+	otpCode, err := a.otp.Issue(ctx, purpose, mail)
+	if err != nil {
+		a.logger.Error("error while issuing otp code", logger.String("purpose", purpose), logger.Error(err))
+		return err
+	}
+
+	err = smtp.SendMail(mail, fmt.Sprintf(msgFormat, otpCode))
+	if err != nil {
+		a.logger.Error("error while sending otp code", logger.String("purpose", purpose), logger.Error(err))
+		if err := a.otp.Cancel(ctx, purpose, mail); err != nil {
+			a.logger.Error("error while cancelling undelivered otp code", logger.String("purpose", purpose), logger.Error(err))
+		}
+		return err
+	}
+
+	return nil
+}
+
+// SendMagicLink mails the user a single-use sign-in link. When nonce is not empty the link
+// only works in the browser that holds it, see LoginWithMagicLink.
+func (a authService) SendMagicLink(ctx context.Context, req models.MagicLinkRequest, nonce string) error {
+	user, err := a.storage.User().GetByMail(ctx, req.Mail)
+	if err != nil {
+		a.logger.Error("gmail address isn't registered", logger.Error(err))
+		return errors.New("gmail address isn't registered")
+	}
+
+	m := make(map[interface{}]interface{})
+
+	m["user_id"] = user.ID
+	m["purpose"] = magicLinkPurpose
+	if nonce != "" {
+		m["nonce"] = hashToken(nonce)
+	}
+
+	token, err := jwt.GenToken(m, jwt.TokenTypeMagicLink, MagicLinkTTL)
+	if err != nil {
+		a.logger.Error("error while generating magic link token", logger.Error(err))
+		return err
+	}
+
+	link := a.cfg.MagicLinkURL + "?token=" + url.QueryEscape(token)
+
+	err = smtp.SendMail(req.Mail, fmt.Sprintf("Follow this link to log in: %s . It expires in %d minutes and works once. Don't share it with anyone", link, int(MagicLinkTTL.Minutes())))
+	if err != nil {
+		a.logger.Error("error while sending magic link", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// LoginWithMagicLink redeems a link sent by SendMagicLink. nonce is the value of the
+// browser's nonce cookie and must match if the link was bound to a browser.
+func (a authService) LoginWithMagicLink(ctx context.Context, token, nonce string, client models.ClientInfo) (models.UserLoginResponse, error) {
+	claims, err := jwt.ExtractClaims(token)
+	if err != nil ||
+		cast.ToString(claims["token_type"]) != jwt.TokenTypeMagicLink ||
+		cast.ToString(claims["purpose"]) != magicLinkPurpose {
+		return models.UserLoginResponse{}, ErrInvalidMagicLink
+	}
+
+	if boundNonce := cast.ToString(claims["nonce"]); boundNonce != "" {
+		if nonce == "" || subtle.ConstantTimeCompare([]byte(boundNonce), []byte(hashToken(nonce))) != 1 {
+			return models.UserLoginResponse{}, ErrInvalidMagicLink
+		}
+	}
+
+	userID := cast.ToString(claims["user_id"])
+
+	firstUse, err := a.redis.SetNX(ctx, "magic_link_used:"+cast.ToString(claims["jti"]), userID, MagicLinkTTL)
+	if err != nil {
+		a.logger.Error("error while marking magic link as used", logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
+	if !firstUse {
+		return models.UserLoginResponse{}, ErrInvalidMagicLink
+	}
+
+	if err := a.checkStatus(ctx, userID); err != nil {
+		return models.UserLoginResponse{}, err
+	}
+
+	userTotp, err := a.storage.Totp().GetByUserID(ctx, userID)
+	if err != nil {
+		a.logger.Error("error while checking two-factor enrollment", logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
+	if userTotp.Confirmed {
+		return a.mfaChallenge(userID, false)
+	}
+
+	roles, err := a.storage.Role().GetByUserID(ctx, userID)
+	if err != nil {
+		a.logger.Error("error while getting roles for magic link login", logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
+
+	resp, err := a.startSession(ctx, userClaims(userID, roles), client)
+	if err != nil {
+		a.logger.Error("error while generating tokens for magic link login", logger.Error(err))
+		return models.UserLoginResponse{}, err
+	}
+
+	return resp, nil
+}
+
+// recordLoginFailure counts a failed password login and mails an unlock link when it locks the account.
+func (a authService) recordLoginFailure(ctx context.Context, mail, ip string) {
+	locked, err := a.lockout.Fail(ctx, mail, ip)
+	if err != nil {
+		a.logger.Error("error while recording failed login", logger.Error(err))
+		return
+	}
+	if !locked {
+		return
+	}
+
+	if _, err := a.storage.User().CheckMailExists(ctx, mail); err != nil {
+		return
+	}
+
+	token, err := generator.Token(32)
+	if err != nil {
+		a.logger.Error("error while generating unlock token", logger.Error(err))
+		return
+	}
+
+	if err := a.redis.Set(ctx, unlockTokenKey(token), mail, lockout.LockoutDuration); err != nil {
+		a.logger.Error("error while saving unlock token", logger.Error(err))
+		return
+	}
+
+	link := a.cfg.UnlockURL + "?token=" + url.QueryEscape(token)
+
+	err = smtp.SendMail(mail, fmt.Sprintf("Your account was locked for %d minutes after too many failed logins. If this was you, follow this link to unlock it now: %s . If it was not, change your password.", int(lockout.LockoutDuration.Minutes()), link))
+	if err != nil {
+		a.logger.Error("error while sending unlock mail", logger.Error(err))
+	}
+}
+
+// UnlockWithToken lifts a lockout with the link mailed by recordLoginFailure.
+func (a authService) UnlockWithToken(ctx context.Context, token string) error {
+	mail, err := a.redis.Get(ctx, unlockTokenKey(token))
+	if err != nil {
+		return ErrInvalidUnlockToken
+	}
+
+	if err := a.redis.Del(ctx, unlockTokenKey(token)); err != nil {
+		a.logger.Error("error while deleting unlock token", logger.Error(err))
+		return err
+	}
+
+	if err := a.lockout.Unlock(ctx, cast.ToString(mail)); err != nil {
+		a.logger.Error("error while unlocking account", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// UnlockUser lifts a lockout of the user, for admins.
+func (a authService) UnlockUser(ctx context.Context, userID string) error {
+	user, err := a.storage.User().GetByID(ctx, userID)
+	if err != nil {
+		a.logger.Error("error while getting user to unlock", logger.Error(err))
+		return err
+	}
+
+	if err := a.lockout.Unlock(ctx, user.Mail); err != nil {
+		a.logger.Error("error while unlocking account", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// IsTokenRevoked reports whether the access token was logged out or its session was revoked.
+func (a authService) IsTokenRevoked(ctx context.Context, info models.AuthInfo) (bool, error) {
+	if info.TokenID == "" || (info.Family == "" && !info.Restricted) {
+		return true, nil
+	}
+
+	revoked, err := a.redis.Exists(ctx, revokedTokenKey(info.TokenID))
+	if err != nil {
+		a.logger.Error("error while checking token denylist", logger.Error(err))
+		return false, err
+	}
+	if revoked {
+		return true, nil
+	}
+
+	if info.Restricted {
+		return false, nil
+	}
+
+	active, err := a.redis.Exists(ctx, refreshFamilyKey(info.Family))
+	if err != nil {
+		a.logger.Error("error while checking refresh token family", logger.Error(err))
+		return false, err
+	}
+
+	return !active, nil
+}
+
+// Logout revokes the presented access token and the refresh token family it belongs to.
+func (a authService) Logout(ctx context.Context, info models.AuthInfo) error {
+	if err := a.revokeToken(ctx, info); err != nil {
+		a.logger.Error("error while revoking access token", logger.Error(err))
+		return err
+	}
+
+	if err := a.redis.Del(ctx, refreshFamilyKey(info.Family)); err != nil {
+		a.logger.Error("error while revoking refresh token family", logger.Error(err))
+		return err
+	}
+
+	if err := a.storage.Session().DeleteByFamily(ctx, info.Family); err != nil {
+		a.logger.Error("error while deleting session", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// LogoutAll revokes every session of the authenticated user.
+func (a authService) LogoutAll(ctx context.Context, info models.AuthInfo) error {
+	if err := a.revokeToken(ctx, info); err != nil {
+		a.logger.Error("error while revoking access token", logger.Error(err))
+		return err
+	}
+
+	if err := a.revokeUserSessions(ctx, info.UserID, ""); err != nil {
+		a.logger.Error("error while revoking user sessions", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// revokeToken puts the token id on the denylist for the rest of the token lifetime.
+func (a authService) revokeToken(ctx context.Context, info models.AuthInfo) error {
+	ttl := time.Until(time.Unix(info.ExpiresAt, 0))
+	if ttl <= 0 {
+		return nil
+	}
+
+	return a.redis.Set(ctx, revokedTokenKey(info.TokenID), info.UserID, ttl)
+}
+
+// revokeUserSessions revokes all refresh token families of the user except keepFamily.
+func (a authService) revokeUserSessions(ctx context.Context, userID, keepFamily string) error {
+	return revokeUserSessions(ctx, a.storage, a.redis, userID, keepFamily)
+}
+
+func revokeUserSessions(ctx context.Context, strg storage.IStorage, redis storage.IRedisStorage, userID, keepFamily string) error {
+	families, err := redis.SMembers(ctx, userFamiliesKey(userID))
+	if err != nil {
+		return err
+	}
+
+	for _, family := range families {
+		if family == keepFamily {
+			continue
+		}
+		if err := redis.Del(ctx, refreshFamilyKey(family)); err != nil {
+			return err
+		}
+	}
+
+	if err := strg.Session().DeleteByUserID(ctx, userID, keepFamily); err != nil {
+		return err
+	}
+
+	if keepFamily == "" {
+		return redis.Del(ctx, userFamiliesKey(userID))
+	}
+
+	return nil
+}
+
+func (a authService) revokeSessionsByMail(ctx context.Context, mail string) error {
+	user, err := a.storage.User().GetByMail(ctx, mail)
+	if err != nil {
+		return err
+	}
+
+	return a.revokeUserSessions(ctx, user.ID, "")
+}
+
+// userClaims builds the custom claims shared by every token issued to a user.
+func userClaims(userID string, roles models.UserRoles) map[interface{}]interface{} {
+	m := make(map[interface{}]interface{})
+
+	m["user_id"] = userID
+	m["user_role"] = primaryRole(roles.Roles)
+	m["roles"] = roles.Roles
+	m["permissions"] = roles.Permissions
+
+	return m
+}
+
+func primaryRole(roles []string) string {
+	for _, role := range roles {
+		if role == config.ADMIN_ROLE {
+			return config.ADMIN_ROLE
+		}
+	}
+	return config.USER_ROLE
+}
+
+func revokedTokenKey(jti string) string {
+	return "revoked_jti:" + jti
+}
+
+func userFamiliesKey(userID string) string {
+	return "user_families:" + userID
+}
+
+func refreshFamilyKey(family string) string {
+	return "refresh_family:" + family
+}
+
+func usedRefreshTokenKey(refreshToken string) string {
+	return "refresh_used:" + hashToken(refreshToken)
+}
+
+func unlockTokenKey(token string) string {
+	return "login_unlock:" + hashToken(token)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+	"user/api/models"
+	"user/pkg/jwt"
+	"user/pkg/logger"
+	"user/pkg/smtp"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cast"
+)
+
+const (
+	// ExportTTL is how long an export and its download link stay available.
+	ExportTTL = 24 * time.Hour
+
+	// exportTimeout bounds how long building an export may take before the job is marked failed.
+	exportTimeout = 10 * time.Minute
+)
+
+var (
+	ErrExportNotFound    = errors.New("export not found or expired")
+	ErrInvalidExportLink = errors.New("invalid or expired export link")
+	ErrExportNotReady    = errors.New("export is not ready yet")
+	ErrExportUnavailable = errors.New("export failed, request a new one")
+)
+
+func exportJobKey(id string) string {
+	return "export_job:" + id
+}
+
+func exportFileKey(id string) string {
+	return "export_file:" + id
+}
+
+// Export starts building a ZIP archive of the user's data in the background.
+// The returned job can be polled with GetExportJob and the user is mailed
+// a download link once the archive is ready.
+func (s userService) Export(ctx context.Context, userID string) (models.ExportJob, error) {
+	job := models.ExportJob{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Status:    models.ExportStatusPending,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	if err := s.saveExportJob(ctx, job); err != nil {
+		s.logger.Error("failed to save export job", logger.Error(err))
+		return models.ExportJob{}, err
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		defer cancel()
+
+		s.runExport(ctx, job)
+	}()
+
+	return job, nil
+}
+
+// runExport collects the user's data, stores the archive and mails the user a signed download link.
+// The job is marked failed unless the archive is stored before ctx is done.
+func (s userService) runExport(ctx context.Context, job models.ExportJob) {
+	job.Status = models.ExportStatusFailed
+	defer func() {
+		if job.Status == models.ExportStatusFailed && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			s.logger.Error("export timed out", logger.String("export_id", job.ID))
+		}
+		// ctx may have expired, which must not keep the final status from being saved.
+		if err := s.saveExportJob(context.WithoutCancel(ctx), job); err != nil {
+			s.logger.Error("failed to save export job", logger.Error(err))
+		}
+	}()
+
+	data, err := s.collectExport(ctx, job.UserID)
+	if err != nil {
+		s.logger.Error("failed to collect user data for export", logger.Error(err))
+		return
+	}
+
+	archive, err := buildExportArchive(data)
+	if err != nil {
+		s.logger.Error("failed to build export archive", logger.Error(err))
+		return
+	}
+
+	if err := s.redis.Set(ctx, exportFileKey(job.ID), archive, ExportTTL); err != nil {
+		s.logger.Error("failed to store export archive", logger.Error(err))
+		return
+	}
+
+	job.Status = models.ExportStatusReady
+
+	m := make(map[interface{}]interface{})
+
+	m["user_id"] = job.UserID
+	m["export_id"] = job.ID
+
+	token, err := jwt.GenToken(m, jwt.TokenTypeExport, ExportTTL)
+	if err != nil {
+		s.logger.Error("failed to generate export token", logger.Error(err))
+		return
+	}
+
+	link := s.cfg.ExportURL + "?token=" + url.QueryEscape(token)
+
+	err = smtp.SendMail(data.Profile.Mail, fmt.Sprintf("Your data export is ready. Download it here: %s . The link expires in %d hours", link, int(ExportTTL.Hours())))
+	if err != nil {
+		s.logger.Error("failed to send export link", logger.Error(err))
+	}
+}
+
+func (s userService) saveExportJob(ctx context.Context, job models.ExportJob) error {
+	jobJSON, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return s.redis.Set(ctx, exportJobKey(job.ID), string(jobJSON), ExportTTL)
+}
+
+// GetExportJob returns the state of an export of the user.
+func (s userService) GetExportJob(ctx context.Context, userID, id string) (models.ExportJob, error) {
+	var job models.ExportJob
+
+	jobJSON, err := s.redis.Get(ctx, exportJobKey(id))
+	if err != nil {
+		return models.ExportJob{}, ErrExportNotFound
+	}
+
+	if err := json.Unmarshal([]byte(cast.ToString(jobJSON)), &job); err != nil {
+		s.logger.Error("failed to unmarshal export job", logger.Error(err))
+		return models.ExportJob{}, err
+	}
+	if job.UserID != userID {
+		return models.ExportJob{}, ErrExportNotFound
+	}
+
+	return job, nil
+}
+
+// DownloadExport returns the archive of an export for a link mailed by Export.
+func (s userService) DownloadExport(ctx context.Context, token string) ([]byte, error) {
+	claims, err := jwt.ExtractClaims(token)
+	if err != nil || cast.ToString(claims["token_type"]) != jwt.TokenTypeExport {
+		return nil, ErrInvalidExportLink
+	}
+
+	job, err := s.GetExportJob(ctx, cast.ToString(claims["user_id"]), cast.ToString(claims["export_id"]))
+	if err != nil {
+		if errors.Is(err, ErrExportNotFound) {
+			return nil, ErrInvalidExportLink
+		}
+		return nil, err
+	}
+
+	switch job.Status {
+	case models.ExportStatusPending:
+		return nil, ErrExportNotReady
+	case models.ExportStatusFailed:
+		return nil, ErrExportUnavailable
+	}
+
+	archive, err := s.redis.Get(ctx, exportFileKey(job.ID))
+	if err != nil {
+		return nil, ErrInvalidExportLink
+	}
+
+	return []byte(cast.ToString(archive)), nil
+}
+
+// collectExport gathers everything stored about the user.
+func (s userService) collectExport(ctx context.Context, userID string) (models.UserExport, error) {
+	data := models.UserExport{ExportedAt: time.Now().UTC().Format(time.RFC3339)}
+
+	user, err := s.storage.User().GetByID(ctx, userID)
+	if err != nil {
+		return data, err
+	}
+	data.Profile = user
+
+	data.Roles, err = s.storage.Role().GetByUserID(ctx, userID)
+	if err != nil {
+		return data, err
+	}
+
+	sessions, err := s.storage.Session().GetAllByUserID(ctx, userID, time.Time{})
+	if err != nil {
+		return data, err
+	}
+	data.Sessions = sessions.Sessions
+
+	history, err := s.storage.User().GetStatusHistory(ctx, userID)
+	if err != nil {
+		return data, err
+	}
+	data.StatusHistory = history.History
+
+	userTotp, err := s.storage.Totp().GetByUserID(ctx, userID)
+	if err != nil {
+		return data, err
+	}
+	data.TwoFactor.Enabled = userTotp.Confirmed
+
+	data.TwoFactor.RecoveryCodesRemaining, err = s.storage.RecoveryCode().CountUnused(ctx, userID)
+	if err != nil {
+		return data, err
+	}
+
+	return data, nil
+}
+
+// buildExportArchive writes data as a ZIP holding one JSON document and a CSV file per table.
+func buildExportArchive(data models.UserExport) ([]byte, error) {
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+
+	w, err := zw.Create("export.json")
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(data); err != nil {
+		return nil, err
+	}
+
+	p := data.Profile
+	profile := [][]string{
+		{"id", "mail", "first_name", "last_name", "phone", "sex", "status", "created_at", "updated_at"},
+		{p.ID, p.Mail, p.FirstName, p.LastName, p.Phone, p.Sex, p.Status, p.CreatedAt, p.UpdatedAt},
+	}
+
+	roles := [][]string{{"role"}}
+	for _, role := range data.Roles.Roles {
+		roles = append(roles, []string{role})
+	}
+
+	sessions := [][]string{{"id", "user_agent", "ip", "created_at", "last_seen_at"}}
+	for _, session := range data.Sessions {
+		sessions = append(sessions, []string{session.ID, session.UserAgent, session.IP, session.CreatedAt, session.LastSeenAt})
+	}
+
+	history := [][]string{{"id", "from_status", "to_status", "reason", "actor_id", "expires_at", "created_at"}}
+	for _, change := range data.StatusHistory {
+		expiresAt := ""
+		if change.ExpiresAt != nil {
+			expiresAt = change.ExpiresAt.UTC().Format(time.RFC3339)
+		}
+		history = append(history, []string{change.ID, change.FromStatus, change.ToStatus, change.Reason, change.ActorID, expiresAt, change.CreatedAt.UTC().Format(time.RFC3339)})
+	}
+
+	twoFactor := [][]string{
+		{"enabled", "recovery_codes_remaining"},
+		{strconv.FormatBool(data.TwoFactor.Enabled), strconv.FormatInt(data.TwoFactor.RecoveryCodesRemaining, 10)},
+	}
+
+	files := []struct {
+		name    string
+		records [][]string
+	}{
+		{"profile.csv", profile},
+		{"roles.csv", roles},
+		{"sessions.csv", sessions},
+		{"status_history.csv", history},
+		{"two_factor.csv", twoFactor},
+	}
+
+	for _, file := range files {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		if err := csv.NewWriter(w).WriteAll(file.records); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"user/pkg/generator"
+	"user/pkg/password"
+	"user/storage"
+)
+
+const (
+	// RecoveryCodeCount is the number of recovery codes issued at a time.
+	RecoveryCodeCount = 10
+
+	recoveryCodeLength = 10
+)
+
+var ErrInvalidRecoveryCode = errors.New("incorrect recovery code")
+
+// recoveryCodeHasher always hashes recovery codes with bcrypt, independently of the algorithm
+// configured for passwords, so stored codes keep verifying when that setting changes.
+var recoveryCodeHasher password.Hasher = password.DefaultBcrypt
+
+// generateRecoveryCodes replaces the user's recovery codes with a new set and returns them in plain text.
+// Only their bcrypt hashes are stored, so this is the only time they can be shown.
+func generateRecoveryCodes(ctx context.Context, strg storage.IStorage, userID string) ([]string, error) {
+	codes := make([]string, 0, RecoveryCodeCount)
+	hashes := make([]string, 0, RecoveryCodeCount)
+
+	for i := 0; i < RecoveryCodeCount; i++ {
+		code, err := generator.Code(recoveryCodeLength, generator.Crockford)
+		if err != nil {
+			return nil, err
+		}
+
+		hash, err := recoveryCodeHasher.Hash(code)
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		hashes = append(hashes, hash)
+	}
+
+	if err := strg.RecoveryCode().Replace(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// consumeRecoveryCode marks the matching unused recovery code of the user as used.
+func consumeRecoveryCode(ctx context.Context, strg storage.IStorage, userID, code string) error {
+	code = normalizeRecoveryCode(code)
+	if len(code) != recoveryCodeLength {
+		return ErrInvalidRecoveryCode
+	}
+
+	unused, err := strg.RecoveryCode().GetUnused(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, recoveryCode := range unused {
+		if recoveryCodeHasher.Verify(recoveryCode.CodeHash, code) != nil {
+			continue
+		}
+
+		used, err := strg.RecoveryCode().MarkUsed(ctx, recoveryCode.ID)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidRecoveryCode
+		}
+
+		return nil
+	}
+
+	return ErrInvalidRecoveryCode
+}
+
+// normalizeRecoveryCode drops separators and maps look-alike characters the way Crockford's base32 does.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', ' ':
+			return -1
+		case 'O':
+			return '0'
+		case 'I', 'L':
+			return '1'
+		}
+		return r
+	}, code)
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"user/config"
 	"user/pkg/logger"
 	"user/storage"
 )
@@ -8,19 +9,25 @@ import (
 type IServiceManager interface {
 	User() userService
 	Auth() authService
+	Session() sessionService
+	Totp() totpService
 }
 
 type Service struct {
 	userService userService
 	auth        authService
+	session     sessionService
+	totp        totpService
 
 	logger logger.ILogger
 }
 
-func New(storage storage.IStorage, log logger.ILogger, redis storage.IRedisStorage) Service {
+func New(cfg config.Config, storage storage.IStorage, log logger.ILogger, redis storage.IRedisStorage) Service {
 	return Service{
-		userService: NewUserService(storage, log, redis),
-		auth:        NewAuthService(storage, log, redis),
+		userService: NewUserService(cfg, storage, log, redis),
+		auth:        NewAuthService(cfg, storage, log, redis),
+		session:     NewSessionService(storage, log, redis),
+		totp:        NewTotpService(storage, log, redis),
 		logger:      log,
 	}
 }
@@ -32,3 +39,11 @@ func (s Service) User() userService {
 func (s Service) Auth() authService {
 	return s.auth
 }
+
+func (s Service) Session() sessionService {
+	return s.session
+}
+
+func (s Service) Totp() totpService {
+	return s.totp
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user/api/models"
+	"user/pkg/jwt"
+	"user/pkg/logger"
+	"user/storage"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type sessionService struct {
+	storage storage.IStorage
+	logger  logger.ILogger
+	redis   storage.IRedisStorage
+}
+
+func NewSessionService(storage storage.IStorage, logger logger.ILogger, redis storage.IRedisStorage) sessionService {
+	return sessionService{
+		storage: storage,
+		logger:  logger,
+		redis:   redis,
+	}
+}
+
+func (s sessionService) GetAll(ctx context.Context, info models.AuthInfo) (models.GetSessionsResponse, error) {
+	sessions, err := s.storage.Session().GetAllByUserID(ctx, info.UserID, time.Now().Add(-jwt.RefreshTokenTTL))
+	if err != nil {
+		s.logger.Error("failed to get user sessions", logger.Error(err))
+		return models.GetSessionsResponse{}, err
+	}
+
+	for i := range sessions.Sessions {
+		sessions.Sessions[i].Current = sessions.Sessions[i].RefreshFamily == info.Family
+	}
+
+	return sessions, nil
+}
+
+// Delete revokes the refresh token family of one of the user's sessions and removes it.
+func (s sessionService) Delete(ctx context.Context, info models.AuthInfo, id string) error {
+	session, err := s.storage.Session().GetByID(ctx, id)
+	if err != nil || session.UserID != info.UserID {
+		return ErrSessionNotFound
+	}
+
+	err = s.redis.Del(ctx, refreshFamilyKey(session.RefreshFamily))
+	if err != nil {
+		s.logger.Error("failed to revoke session refresh token family", logger.Error(err))
+		return err
+	}
+
+	err = s.storage.Session().Delete(ctx, id)
+	if err != nil {
+		s.logger.Error("failed to delete session", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"user/api/models"
+	"user/pkg/logger"
+	"user/storage"
+)
+
+var (
+	ErrInvalidStatus       = errors.New("unknown user status")
+	ErrStatusTransition    = errors.New("user status transition is not allowed")
+	ErrInvalidStatusExpiry = errors.New("only suspensions can expire and expires_at must be in the future")
+)
+
+// statusTransitions lists the statuses a user may be moved to from each status.
+// A deleted user can only be brought back by restoring it.
+var statusTransitions = map[string][]string{
+	models.StatusPendingVerification: {models.StatusActive, models.StatusBanned, models.StatusDeleted},
+	models.StatusActive:              {models.StatusSuspended, models.StatusBanned, models.StatusDeleted},
+	models.StatusSuspended:           {models.StatusActive, models.StatusBanned, models.StatusDeleted},
+	models.StatusBanned:              {models.StatusActive, models.StatusDeleted},
+	models.StatusDeleted:             {},
+}
+
+const suspensionExpiredReason = "suspension expired"
+
+func canTransition(from, to string) bool {
+	for _, status := range statusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeStatus moves a user to another status on behalf of actor. Every session of the user
+// is revoked unless the new status is active.
+func (a authService) ChangeStatus(ctx context.Context, actor models.AuthInfo, req models.ChangeStatus) (string, error) {
+	if _, ok := statusTransitions[req.Status]; !ok {
+		return "", ErrInvalidStatus
+	}
+	if req.ExpiresAt != nil && (req.Status != models.StatusSuspended || !req.ExpiresAt.After(time.Now())) {
+		return "", ErrInvalidStatusExpiry
+	}
+
+	current, err := a.storage.User().GetStatus(ctx, req.ID)
+	if err != nil {
+		a.logger.Error("failed to get user status", logger.Error(err))
+		return "", err
+	}
+	if !canTransition(current.Status, req.Status) {
+		return "", fmt.Errorf("%w: %s to %s", ErrStatusTransition, current.Status, req.Status)
+	}
+
+	result, err := a.storage.User().ChangeStatus(ctx, req, current.Status, actor.UserID)
+	if err != nil {
+		a.logger.Error("failed to change user status", logger.Error(err))
+		return "", err
+	}
+
+	if req.Status != models.StatusActive {
+		if err := a.revokeUserSessions(ctx, req.ID, ""); err != nil {
+			a.logger.Error("failed to revoke sessions after status change", logger.Error(err))
+			return "", err
+		}
+	}
+
+	if err := a.redis.Del(ctx, "user_id:"+req.ID); err != nil {
+		a.logger.Error("failed to delete user data from Redis", logger.Error(err))
+	}
+
+	return result, nil
+}
+
+func (a authService) GetStatusHistory(ctx context.Context, userID string) (models.GetStatusHistoryResponse, error) {
+	history, err := a.storage.User().GetStatusHistory(ctx, userID)
+	if err != nil {
+		a.logger.Error("failed to get user status history", logger.Error(err))
+		return models.GetStatusHistoryResponse{}, err
+	}
+	return history, nil
+}
+
+// checkStatus fails with ErrUserInactive unless the user may log in.
+// A suspension whose expiry has passed is lifted here, on the user's next login.
+func (a authService) checkStatus(ctx context.Context, userID string) error {
+	status, err := a.storage.User().GetStatus(ctx, userID)
+	if err != nil {
+		a.logger.Error("error while getting user status", logger.Error(err))
+		return err
+	}
+
+	if status.Status == models.StatusSuspended && status.ExpiresAt != nil && !status.ExpiresAt.After(time.Now()) {
+		_, err := a.storage.User().ChangeStatus(ctx, models.ChangeStatus{
+			ID:     userID,
+			Status: models.StatusActive,
+			Reason: suspensionExpiredReason,
+		}, status.Status, "")
+		if errors.Is(err, storage.ErrStatusChanged) {
+			return a.checkStatus(ctx, userID)
+		}
+		if err != nil {
+			a.logger.Error("error while lifting expired suspension", logger.Error(err))
+			return err
+		}
+		return nil
+	}
+
+	if status.Status != models.StatusActive {
+		return fmt.Errorf("%w: %s", ErrUserInactive, status.Status)
+	}
+
+	return nil
+}
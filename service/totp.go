@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+	"user/api/models"
+	"user/pkg/encrypt"
+	"user/pkg/logger"
+	"user/pkg/totp"
+	"user/storage"
+
+	"github.com/skip2/go-qrcode"
+)
+
+var (
+	ErrTotpAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTotpNotEnrolled    = errors.New("two-factor authentication is not enrolled")
+	ErrInvalidTotpCode    = errors.New("incorrect two-factor authentication code")
+	ErrTooManyTotpCodes   = errors.New("too many incorrect two-factor authentication codes, try again later")
+)
+
+const (
+	// totpMaxAttempts is how many codes a signed-in user may try within totpLockout
+	// when confirming, disabling or regenerating recovery codes.
+	totpMaxAttempts = 5
+	totpLockout     = 15 * time.Minute
+)
+
+type totpService struct {
+	storage storage.IStorage
+	logger  logger.ILogger
+	redis   storage.IRedisStorage
+}
+
+func NewTotpService(storage storage.IStorage, logger logger.ILogger, redis storage.IRedisStorage) totpService {
+	return totpService{
+		storage: storage,
+		logger:  logger,
+		redis:   redis,
+	}
+}
+
+// Enroll creates a new pending TOTP secret for the user. It becomes active after Confirm.
+func (t totpService) Enroll(ctx context.Context, info models.AuthInfo) (models.TotpEnrollResponse, error) {
+	current, err := t.storage.Totp().GetByUserID(ctx, info.UserID)
+	if err != nil {
+		t.logger.Error("failed to get totp enrollment", logger.Error(err))
+		return models.TotpEnrollResponse{}, err
+	}
+	if current.Confirmed {
+		return models.TotpEnrollResponse{}, ErrTotpAlreadyEnabled
+	}
+
+	user, err := t.storage.User().GetByID(ctx, info.UserID)
+	if err != nil {
+		t.logger.Error("failed to get user for totp enrollment", logger.Error(err))
+		return models.TotpEnrollResponse{}, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.logger.Error("failed to generate totp secret", logger.Error(err))
+		return models.TotpEnrollResponse{}, err
+	}
+
+	encrypted, err := encrypt.Encrypt(secret)
+	if err != nil {
+		t.logger.Error("failed to encrypt totp secret", logger.Error(err))
+		return models.TotpEnrollResponse{}, err
+	}
+
+	if err := t.storage.Totp().Upsert(ctx, info.UserID, encrypted); err != nil {
+		t.logger.Error("failed to save totp secret", logger.Error(err))
+		return models.TotpEnrollResponse{}, err
+	}
+
+	uri := totp.URI(user.Mail, secret)
+
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		t.logger.Error("failed to generate totp qr code", logger.Error(err))
+		return models.TotpEnrollResponse{}, err
+	}
+
+	return models.TotpEnrollResponse{
+		Secret: secret,
+		URI:    uri,
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// Confirm enables a pending TOTP enrollment once the user proves the authenticator app works
+// and returns a fresh set of recovery codes.
+func (t totpService) Confirm(ctx context.Context, info models.AuthInfo, code string) (models.RecoveryCodesResponse, error) {
+	current, err := t.storage.Totp().GetByUserID(ctx, info.UserID)
+	if err != nil {
+		t.logger.Error("failed to get totp enrollment", logger.Error(err))
+		return models.RecoveryCodesResponse{}, err
+	}
+	if current.Secret == "" {
+		return models.RecoveryCodesResponse{}, ErrTotpNotEnrolled
+	}
+	if current.Confirmed {
+		return models.RecoveryCodesResponse{}, ErrTotpAlreadyEnabled
+	}
+
+	if err := t.checkTotp(ctx, current, code); err != nil {
+		return models.RecoveryCodesResponse{}, err
+	}
+
+	if err := t.storage.Totp().Confirm(ctx, info.UserID); err != nil {
+		t.logger.Error("failed to confirm totp", logger.Error(err))
+		return models.RecoveryCodesResponse{}, err
+	}
+
+	codes, err := generateRecoveryCodes(ctx, t.storage, info.UserID)
+	if err != nil {
+		t.logger.Error("failed to generate recovery codes", logger.Error(err))
+		return models.RecoveryCodesResponse{}, err
+	}
+
+	return models.RecoveryCodesResponse{Codes: codes}, nil
+}
+
+// RegenerateRecoveryCodes invalidates the user's recovery codes and issues new ones.
+// A valid current TOTP code is required.
+func (t totpService) RegenerateRecoveryCodes(ctx context.Context, info models.AuthInfo, code string) (models.RecoveryCodesResponse, error) {
+	current, err := t.storage.Totp().GetByUserID(ctx, info.UserID)
+	if err != nil {
+		t.logger.Error("failed to get totp enrollment", logger.Error(err))
+		return models.RecoveryCodesResponse{}, err
+	}
+	if !current.Confirmed {
+		return models.RecoveryCodesResponse{}, ErrTotpNotEnrolled
+	}
+
+	if err := t.checkTotp(ctx, current, code); err != nil {
+		return models.RecoveryCodesResponse{}, err
+	}
+
+	codes, err := generateRecoveryCodes(ctx, t.storage, info.UserID)
+	if err != nil {
+		t.logger.Error("failed to generate recovery codes", logger.Error(err))
+		return models.RecoveryCodesResponse{}, err
+	}
+
+	return models.RecoveryCodesResponse{Codes: codes}, nil
+}
+
+// RecoveryCodesCount returns how many unused recovery codes the user has left.
+func (t totpService) RecoveryCodesCount(ctx context.Context, info models.AuthInfo) (models.RecoveryCodesCount, error) {
+	current, err := t.storage.Totp().GetByUserID(ctx, info.UserID)
+	if err != nil {
+		t.logger.Error("failed to get totp enrollment", logger.Error(err))
+		return models.RecoveryCodesCount{}, err
+	}
+	if !current.Confirmed {
+		return models.RecoveryCodesCount{}, ErrTotpNotEnrolled
+	}
+
+	remaining, err := t.storage.RecoveryCode().CountUnused(ctx, info.UserID)
+	if err != nil {
+		t.logger.Error("failed to count recovery codes", logger.Error(err))
+		return models.RecoveryCodesCount{}, err
+	}
+
+	return models.RecoveryCodesCount{Remaining: remaining}, nil
+}
+
+// Disable turns TOTP off. A valid current code is required.
+func (t totpService) Disable(ctx context.Context, info models.AuthInfo, code string) error {
+	current, err := t.storage.Totp().GetByUserID(ctx, info.UserID)
+	if err != nil {
+		t.logger.Error("failed to get totp enrollment", logger.Error(err))
+		return err
+	}
+	if !current.Confirmed {
+		return ErrTotpNotEnrolled
+	}
+
+	if err := t.checkTotp(ctx, current, code); err != nil {
+		return err
+	}
+
+	if err := t.storage.Totp().Delete(ctx, info.UserID); err != nil {
+		t.logger.Error("failed to delete totp", logger.Error(err))
+		return err
+	}
+
+	if err := t.storage.RecoveryCode().DeleteByUserID(ctx, info.UserID); err != nil {
+		t.logger.Error("failed to delete recovery codes", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// checkTotp verifies a code the signed-in user entered to manage two-factor authentication.
+// Attempts are counted per user and fail with ErrTooManyTotpCodes once totpMaxAttempts is
+// exceeded, until totpLockout has passed since the first of them. A valid code resets the count.
+func (t totpService) checkTotp(ctx context.Context, userTotp models.UserTotp, code string) error {
+	key := "totp_attempts:" + userTotp.UserID
+
+	attempts, err := t.redis.Incr(ctx, key, totpLockout)
+	if err != nil {
+		t.logger.Error("failed to count totp attempts", logger.Error(err))
+		return err
+	}
+	if attempts > totpMaxAttempts {
+		return ErrTooManyTotpCodes
+	}
+
+	if err := verifyTotp(ctx, t.redis, userTotp, code); err != nil {
+		return err
+	}
+
+	if err := t.redis.Del(ctx, key); err != nil {
+		t.logger.Error("failed to reset totp attempts", logger.Error(err))
+	}
+
+	return nil
+}
+
+// verifyTotp checks code against the user's secret and rejects a code that was already used.
+func verifyTotp(ctx context.Context, redis storage.IRedisStorage, userTotp models.UserTotp, code string) error {
+	secret, err := encrypt.Decrypt(userTotp.Secret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return ErrInvalidTotpCode
+	}
+
+	key := fmt.Sprintf("totp_used:%s:%d", userTotp.UserID, step)
+	firstUse, err := redis.SetNX(ctx, key, 1, time.Duration(2*totp.Skew+1)*totp.Period*time.Second)
+	if err != nil {
+		return err
+	}
+	if !firstUse {
+		return ErrInvalidTotpCode
+	}
+
+	return nil
+}
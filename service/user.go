@@ -3,19 +3,23 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"time"
 	"user/api/models"
+	"user/config"
 	"user/pkg/logger"
 	"user/storage"
 )
 
 type userService struct {
+	cfg     config.Config
 	storage storage.IStorage
 	logger  logger.ILogger
 	redis   storage.IRedisStorage
 }
 
-func NewUserService(storage storage.IStorage, logger logger.ILogger, redis storage.IRedisStorage) userService {
+func NewUserService(cfg config.Config, storage storage.IStorage, logger logger.ILogger, redis storage.IRedisStorage) userService {
 	return userService{
+		cfg:     cfg,
 		storage: storage,
 		logger:  logger,
 		redis:   redis,
@@ -76,13 +80,20 @@ func (s userService) GetAll(ctx context.Context, req models.GetAllUsersRequest)
 	return users, nil
 }
 
-func (s userService) Delete(ctx context.Context, id string) error {
-	err := s.storage.User().Delete(ctx, id)
+// Delete soft deletes a user on behalf of actorID and revokes its sessions.
+// The user can be restored within the retention window.
+func (s userService) Delete(ctx context.Context, id, actorID string) error {
+	err := s.storage.User().Delete(ctx, id, actorID)
 	if err != nil {
 		s.logger.Error("failed to delete user", logger.Error(err))
 		return err
 	}
 
+	if err := revokeUserSessions(ctx, s.storage, s.redis, id, ""); err != nil {
+		s.logger.Error("failed to revoke sessions of deleted user", logger.Error(err))
+		return err
+	}
+
 	err = s.redis.Del(ctx, "user_id:"+id)
 	if err != nil {
 		s.logger.Error("failed to delete user data from Redis", logger.Error(err))
@@ -90,3 +101,48 @@ func (s userService) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// Restore brings back a user deleted less than the retention window ago and returns its status.
+func (s userService) Restore(ctx context.Context, id, actorID string) (string, error) {
+	status, err := s.storage.User().Restore(ctx, id, actorID, time.Now().Add(-s.cfg.UserRetention))
+	if err != nil {
+		s.logger.Error("failed to restore user", logger.Error(err))
+		return "", err
+	}
+	return status, nil
+}
+
+// PurgeDeleted hard deletes or anonymises, depending on the purge mode, the users
+// deleted longer than the retention window ago.
+func (s userService) PurgeDeleted(ctx context.Context) (int64, error) {
+	anonymise := s.cfg.UserPurgeMode != "delete"
+
+	purged, err := s.storage.User().Purge(ctx, time.Now().Add(-s.cfg.UserRetention), anonymise)
+	if err != nil {
+		s.logger.Error("failed to purge deleted users", logger.Error(err))
+		return 0, err
+	}
+	return purged, nil
+}
+
+// RunPurge calls PurgeDeleted every purge interval until ctx is done.
+// A purge interval of zero disables purging.
+func (s userService) RunPurge(ctx context.Context) {
+	if s.cfg.UserPurgeInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.UserPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if purged, err := s.PurgeDeleted(ctx); err == nil && purged > 0 {
+			s.logger.Info("purged deleted users", logger.Any("count", purged))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
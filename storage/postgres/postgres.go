@@ -51,11 +51,35 @@ func (s Store) CloseDB() {
 }
 
 func (s Store) User() storage.IUserStorage {
-	newUser := NewUserRepo(s.Pool, s.logger, s.redis)
+	newUser := NewUserRepo(s.Pool, s.logger, s.redis, s.cfg.PasswordHistorySize)
 
 	return &newUser
 }
 
+func (s Store) Session() storage.ISessionStorage {
+	newSession := NewSessionRepo(s.Pool, s.logger)
+
+	return &newSession
+}
+
+func (s Store) Role() storage.IRoleStorage {
+	newRole := NewRoleRepo(s.Pool, s.logger)
+
+	return &newRole
+}
+
+func (s Store) Totp() storage.ITotpStorage {
+	newTotp := NewTotpRepo(s.Pool, s.logger)
+
+	return &newTotp
+}
+
+func (s Store) RecoveryCode() storage.IRecoveryCodeStorage {
+	newRecoveryCode := NewRecoveryCodeRepo(s.Pool, s.logger)
+
+	return &newRecoveryCode
+}
+
 func (s Store) Redis() storage.IRedisStorage {
 	return redis.New(s.cfg)
 }
@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"context"
+	"user/api/models"
+	"user/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type RecoveryCodeRepo struct {
+	db     *pgxpool.Pool
+	logger logger.ILogger
+}
+
+func NewRecoveryCodeRepo(db *pgxpool.Pool, log logger.ILogger) RecoveryCodeRepo {
+	return RecoveryCodeRepo{
+		db:     db,
+		logger: log,
+	}
+}
+
+// Replace deletes every recovery code of the user and stores the given hashes instead.
+func (r *RecoveryCodeRepo) Replace(ctx context.Context, userID string, hashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		r.logger.Error("failed to begin recovery codes transaction", logger.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM "RecoveryCodes" WHERE user_id = $1`, userID)
+	if err != nil {
+		r.logger.Error("failed to delete recovery codes from database", logger.Error(err))
+		return err
+	}
+
+	query := `INSERT INTO "RecoveryCodes" (
+		id,
+		user_id,
+		code_hash,
+		created_at
+	) VALUES ($1, $2, $3, CURRENT_TIMESTAMP)`
+
+	for _, hash := range hashes {
+		_, err = tx.Exec(ctx, query, uuid.New().String(), userID, hash)
+		if err != nil {
+			r.logger.Error("failed to create recovery code in database", logger.Error(err))
+			return err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		r.logger.Error("failed to commit recovery codes transaction", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *RecoveryCodeRepo) GetUnused(ctx context.Context, userID string) ([]models.RecoveryCode, error) {
+	var codes []models.RecoveryCode
+
+	query := `SELECT
+		id,
+		user_id,
+		code_hash
+	FROM "RecoveryCodes"
+	WHERE user_id = $1 AND used_at IS NULL`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		r.logger.Error("failed to get recovery codes from database", logger.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var code models.RecoveryCode
+
+		if err := rows.Scan(&code.ID, &code.UserID, &code.CodeHash); err != nil {
+			r.logger.Error("failed to scan recovery codes from database", logger.Error(err))
+			return nil, err
+		}
+
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+func (r *RecoveryCodeRepo) CountUnused(ctx context.Context, userID string) (int64, error) {
+	var count int64
+
+	query := `SELECT COUNT(id) FROM "RecoveryCodes" WHERE user_id = $1 AND used_at IS NULL`
+
+	err := r.db.QueryRow(ctx, query, userID).Scan(&count)
+	if err != nil {
+		r.logger.Error("failed to count recovery codes in database", logger.Error(err))
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// MarkUsed consumes a recovery code. It reports false if the code was already used.
+func (r *RecoveryCodeRepo) MarkUsed(ctx context.Context, id string) (bool, error) {
+	query := `UPDATE "RecoveryCodes" SET
+		used_at = CURRENT_TIMESTAMP
+	WHERE id = $1 AND used_at IS NULL`
+
+	tag, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		r.logger.Error("failed to mark recovery code as used in database", logger.Error(err))
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *RecoveryCodeRepo) DeleteByUserID(ctx context.Context, userID string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM "RecoveryCodes" WHERE user_id = $1`, userID)
+	if err != nil {
+		r.logger.Error("failed to delete recovery codes from database", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
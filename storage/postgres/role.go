@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"user/api/models"
+	"user/pkg/logger"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const userRolesColumns = `ARRAY(
+			SELECT r.name FROM "UserRoles" ur JOIN "Roles" r ON r.id = ur.role_id
+			WHERE ur.user_id = u.id ORDER BY r.name
+		),
+		ARRAY(
+			SELECT DISTINCT unnest(r.permissions) FROM "UserRoles" ur JOIN "Roles" r ON r.id = ur.role_id
+			WHERE ur.user_id = u.id
+		)`
+
+type RoleRepo struct {
+	db     *pgxpool.Pool
+	logger logger.ILogger
+}
+
+func NewRoleRepo(db *pgxpool.Pool, log logger.ILogger) RoleRepo {
+	return RoleRepo{
+		db:     db,
+		logger: log,
+	}
+}
+
+func (r *RoleRepo) GetByUserID(ctx context.Context, userID string) (models.UserRoles, error) {
+	var roles models.UserRoles
+
+	query := `SELECT
+		` + userRolesColumns + `
+	FROM "Users" u
+	WHERE u.id = $1`
+
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&roles.Roles,
+		&roles.Permissions,
+	)
+	if err != nil {
+		r.logger.Error("failed to get user roles from database", logger.Error(err))
+		return models.UserRoles{}, err
+	}
+
+	return roles, nil
+}
+
+func (r *RoleRepo) Assign(ctx context.Context, userID, role string) error {
+	query := `INSERT INTO "UserRoles" (user_id, role_id)
+	SELECT $1, id FROM "Roles" WHERE name = $2
+	ON CONFLICT DO NOTHING`
+
+	_, err := r.db.Exec(ctx, query, userID, role)
+	if err != nil {
+		r.logger.Error("failed to assign role to user in database", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
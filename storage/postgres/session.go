@@ -0,0 +1,201 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"user/api/models"
+	"user/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SessionRepo struct {
+	db     *pgxpool.Pool
+	logger logger.ILogger
+}
+
+func NewSessionRepo(db *pgxpool.Pool, log logger.ILogger) SessionRepo {
+	return SessionRepo{
+		db:     db,
+		logger: log,
+	}
+}
+
+func (s *SessionRepo) Create(ctx context.Context, session models.CreateSession) (string, error) {
+	id := uuid.New().String()
+	query := `INSERT INTO "Sessions" (
+		id,
+		user_id,
+		refresh_family,
+		user_agent,
+		ip,
+		created_at,
+		last_seen_at
+	) VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+
+	_, err := s.db.Exec(ctx, query,
+		id,
+		session.UserID,
+		session.RefreshFamily,
+		session.UserAgent,
+		session.IP,
+	)
+	if err != nil {
+		s.logger.Error("failed to create session in database", logger.Error(err))
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (s *SessionRepo) GetByID(ctx context.Context, id string) (models.Session, error) {
+	var (
+		session    models.Session
+		userAgent  sql.NullString
+		ip         sql.NullString
+		createdAt  sql.NullString
+		lastSeenAt sql.NullString
+	)
+
+	query := `SELECT
+		id,
+		user_id,
+		refresh_family,
+		user_agent,
+		ip,
+		created_at,
+		last_seen_at
+	FROM "Sessions"
+	WHERE id = $1`
+
+	err := s.db.QueryRow(ctx, query, id).Scan(
+		&session.ID,
+		&session.UserID,
+		&session.RefreshFamily,
+		&userAgent,
+		&ip,
+		&createdAt,
+		&lastSeenAt,
+	)
+	if err != nil {
+		s.logger.Error("failed to scan session by ID from database", logger.Error(err))
+		return models.Session{}, err
+	}
+
+	session.UserAgent = userAgent.String
+	session.IP = ip.String
+	session.CreatedAt = createdAt.String
+	session.LastSeenAt = lastSeenAt.String
+
+	return session, nil
+}
+
+func (s *SessionRepo) GetAllByUserID(ctx context.Context, userID string, activeSince time.Time) (models.GetSessionsResponse, error) {
+	var (
+		resp       = models.GetSessionsResponse{}
+		userAgent  sql.NullString
+		ip         sql.NullString
+		createdAt  sql.NullString
+		lastSeenAt sql.NullString
+	)
+
+	query := `SELECT
+		id,
+		user_id,
+		refresh_family,
+		user_agent,
+		ip,
+		created_at,
+		last_seen_at
+	FROM "Sessions"
+	WHERE user_id = $1 AND last_seen_at > $2
+	ORDER BY last_seen_at DESC`
+
+	rows, err := s.db.Query(ctx, query, userID, activeSince)
+	if err != nil {
+		s.logger.Error("failed to get sessions from database", logger.Error(err))
+		return resp, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var session models.Session
+
+		err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.RefreshFamily,
+			&userAgent,
+			&ip,
+			&createdAt,
+			&lastSeenAt,
+		)
+		if err != nil {
+			s.logger.Error("failed to scan sessions from database", logger.Error(err))
+			return models.GetSessionsResponse{}, err
+		}
+
+		session.UserAgent = userAgent.String
+		session.IP = ip.String
+		session.CreatedAt = createdAt.String
+		session.LastSeenAt = lastSeenAt.String
+
+		resp.Sessions = append(resp.Sessions, session)
+	}
+	resp.Count = int64(len(resp.Sessions))
+
+	return resp, nil
+}
+
+func (s *SessionRepo) UpdateLastSeen(ctx context.Context, family string) error {
+	query := `UPDATE "Sessions" SET
+		last_seen_at = CURRENT_TIMESTAMP
+	WHERE refresh_family = $1`
+
+	_, err := s.db.Exec(ctx, query, family)
+	if err != nil {
+		s.logger.Error("failed to update session last seen in database", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *SessionRepo) Delete(ctx context.Context, id string) error {
+	query := `DELETE FROM "Sessions" WHERE id = $1`
+
+	_, err := s.db.Exec(ctx, query, id)
+	if err != nil {
+		s.logger.Error("failed to delete session from database", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *SessionRepo) DeleteByFamily(ctx context.Context, family string) error {
+	query := `DELETE FROM "Sessions" WHERE refresh_family = $1`
+
+	_, err := s.db.Exec(ctx, query, family)
+	if err != nil {
+		s.logger.Error("failed to delete session by family from database", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// DeleteByUserID removes every session of the user except the one bound to exceptFamily.
+func (s *SessionRepo) DeleteByUserID(ctx context.Context, userID, exceptFamily string) error {
+	query := `DELETE FROM "Sessions" WHERE user_id = $1 AND refresh_family::text <> $2`
+
+	_, err := s.db.Exec(ctx, query, userID, exceptFamily)
+	if err != nil {
+		s.logger.Error("failed to delete user sessions from database", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"user/api/models"
+	"user/pkg/logger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TotpRepo struct {
+	db     *pgxpool.Pool
+	logger logger.ILogger
+}
+
+func NewTotpRepo(db *pgxpool.Pool, log logger.ILogger) TotpRepo {
+	return TotpRepo{
+		db:     db,
+		logger: log,
+	}
+}
+
+// Upsert stores a new, unconfirmed secret for the user, replacing any previous one.
+func (t *TotpRepo) Upsert(ctx context.Context, userID, secret string) error {
+	query := `INSERT INTO "UserTotp" (
+		user_id,
+		secret,
+		confirmed_at,
+		created_at
+	) VALUES ($1, $2, NULL, CURRENT_TIMESTAMP)
+	ON CONFLICT (user_id) DO UPDATE SET
+		secret = EXCLUDED.secret,
+		confirmed_at = NULL,
+		created_at = CURRENT_TIMESTAMP`
+
+	_, err := t.db.Exec(ctx, query, userID, secret)
+	if err != nil {
+		t.logger.Error("failed to save totp secret in database", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// GetByUserID returns the user's TOTP enrollment. A zero value is returned if the user has none.
+func (t *TotpRepo) GetByUserID(ctx context.Context, userID string) (models.UserTotp, error) {
+	var totp models.UserTotp
+
+	query := `SELECT
+		user_id,
+		secret,
+		confirmed_at IS NOT NULL
+	FROM "UserTotp"
+	WHERE user_id = $1`
+
+	err := t.db.QueryRow(ctx, query, userID).Scan(
+		&totp.UserID,
+		&totp.Secret,
+		&totp.Confirmed,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.UserTotp{}, nil
+		}
+		t.logger.Error("failed to get totp secret from database", logger.Error(err))
+		return models.UserTotp{}, err
+	}
+
+	return totp, nil
+}
+
+func (t *TotpRepo) Confirm(ctx context.Context, userID string) error {
+	query := `UPDATE "UserTotp" SET
+		confirmed_at = CURRENT_TIMESTAMP
+	WHERE user_id = $1`
+
+	_, err := t.db.Exec(ctx, query, userID)
+	if err != nil {
+		t.logger.Error("failed to confirm totp in database", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (t *TotpRepo) Delete(ctx context.Context, userID string) error {
+	query := `DELETE FROM "UserTotp" WHERE user_id = $1`
+
+	_, err := t.db.Exec(ctx, query, userID)
+	if err != nil {
+		t.logger.Error("failed to delete totp from database", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
@@ -8,26 +8,39 @@ import (
 	"fmt"
 	"time"
 	"user/api/models"
+	"user/config"
 	"user/pkg/logger"
 	"user/pkg/password"
 	"user/storage"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	userDeletedReason  = "account deleted"
+	userRestoredReason = "account restored"
+
+	// uniqueViolation is the SQLSTATE of a unique constraint violation.
+	uniqueViolation = "23505"
 )
 
 type UserRepo struct {
 	db     *pgxpool.Pool
 	logger logger.ILogger
 	redis  storage.IRedisStorage
+	// passwordHistory is how many recent passwords may not be reused.
+	passwordHistory int
 }
 
-func NewUserRepo(db *pgxpool.Pool, log logger.ILogger, redis storage.IRedisStorage) UserRepo {
+func NewUserRepo(db *pgxpool.Pool, log logger.ILogger, redis storage.IRedisStorage, passwordHistory int) UserRepo {
 	return UserRepo{
-		db:     db,
-		logger: log,
-		redis:  redis,
+		db:              db,
+		logger:          log,
+		redis:           redis,
+		passwordHistory: passwordHistory,
 	}
 }
 
@@ -41,11 +54,19 @@ func (c *UserRepo) Create(ctx context.Context, user models.CreateUser) (string,
 		password,
         phone,
         sex,
+		password_changed_at,
         created_at,
         updated_at
-    ) VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+    ) VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
 
-	_, err := c.db.Exec(ctx, query,
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		c.logger.Error("failed to begin create user transaction", logger.Error(err))
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, query,
 		id,
 		user.Mail,
 		user.FirstName,
@@ -59,6 +80,22 @@ func (c *UserRepo) Create(ctx context.Context, user models.CreateUser) (string,
 		return "", err
 	}
 
+	_, err = tx.Exec(ctx, `INSERT INTO "UserRoles" (user_id, role_id)
+	SELECT $1, id FROM "Roles" WHERE name = $2`, id, config.USER_ROLE)
+	if err != nil {
+		c.logger.Error("failed to assign default role to user in database", logger.Error(err))
+		return "", err
+	}
+
+	if err = c.addPasswordHistory(ctx, tx, id, user.Password); err != nil {
+		return "", err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		c.logger.Error("failed to commit create user transaction", logger.Error(err))
+		return "", err
+	}
+
 	UserJSON, err := json.Marshal(user)
 	if err != nil {
 		c.logger.Error("failed to marshal User data for Redis", logger.Error(err))
@@ -79,7 +116,7 @@ func (c *UserRepo) Update(ctx context.Context, user models.UpdateUser, id string
 		mail = $3,
 		phone = $4,
 		updated_at = $5
-	WHERE id = $6`
+	WHERE id = $6 AND deleted_at IS NULL`
 
 	_, err := c.db.Exec(ctx, query,
 		user.FirstName,
@@ -105,9 +142,8 @@ func (c *UserRepo) GetByID(ctx context.Context, id string) (models.User, error)
 		lastname  sql.NullString
 		phone     sql.NullString
 		mail      sql.NullString
-		password  sql.NullString
 		sex       sql.NullString
-		active    sql.NullBool
+		status    sql.NullString
 		createdat sql.NullString
 		updatedat sql.NullString
 	)
@@ -117,14 +153,13 @@ func (c *UserRepo) GetByID(ctx context.Context, id string) (models.User, error)
 		mail,
 		first_name,
 		last_name,
-		password,
 		phone,
 		sex,
-		active,
+		status,
 		created_at,
 		updated_at
 	FROM "Users" 
-	WHERE id = $1`
+	WHERE id = $1 AND deleted_at IS NULL`
 
 	row := c.db.QueryRow(ctx, query, id)
 
@@ -133,10 +168,9 @@ func (c *UserRepo) GetByID(ctx context.Context, id string) (models.User, error)
 		&mail,
 		&firstname,
 		&lastname,
-		&password,
 		&phone,
 		&sex,
-		&active,
+		&status,
 		&createdat,
 		&updatedat,
 	)
@@ -152,10 +186,9 @@ func (c *UserRepo) GetByID(ctx context.Context, id string) (models.User, error)
 	user.Mail = mail.String
 	user.FirstName = firstname.String
 	user.LastName = lastname.String
-	user.Password = password.String
 	user.Phone = phone.String
 	user.Sex = sex.String
-	user.Active = active.Bool
+	user.Status = status.String
 	user.CreatedAt = createdat.String
 	user.UpdatedAt = updatedat.String
 
@@ -170,19 +203,17 @@ func (c *UserRepo) GetAll(ctx context.Context, req models.GetAllUsersRequest) (m
 		lastname  sql.NullString
 		phone     sql.NullString
 		mail      sql.NullString
-		password  sql.NullString
 		sex       sql.NullString
-		active    sql.NullBool
+		status    sql.NullString
 		createdat sql.NullString
 		updatedat sql.NullString
 		count     sql.NullInt64
 	)
 	offset := (req.Page - 1) * req.Limit
 
+	filter = ` WHERE deleted_at IS NULL`
 	if req.Search != "" {
-		filter = fmt.Sprintf(` WHERE (first_name ILIKE '%%%v%%' OR last_name ILIKE '%%%v%%')`, req.Search, req.Search)
-	} else {
-		filter = ""
+		filter += fmt.Sprintf(` AND (first_name ILIKE '%%%v%%' OR last_name ILIKE '%%%v%%')`, req.Search, req.Search)
 	}
 
 	filter += fmt.Sprintf(" OFFSET %v LIMIT %v", offset, req.Limit)
@@ -192,10 +223,9 @@ func (c *UserRepo) GetAll(ctx context.Context, req models.GetAllUsersRequest) (m
 		mail,
 		first_name,
 		last_name,
-		password,
 		phone,
 		sex,
-		active,
+		status,
 		created_at,
 		updated_at
 	FROM "Users"` + filter
@@ -215,10 +245,9 @@ func (c *UserRepo) GetAll(ctx context.Context, req models.GetAllUsersRequest) (m
 			&mail,
 			&firstname,
 			&lastname,
-			&password,
 			&phone,
 			&sex,
-			&active,
+			&status,
 			&createdat,
 			&updatedat,
 		)
@@ -230,17 +259,16 @@ func (c *UserRepo) GetAll(ctx context.Context, req models.GetAllUsersRequest) (m
 		user.Mail = mail.String
 		user.FirstName = firstname.String
 		user.LastName = lastname.String
-		user.Password = password.String
 		user.Phone = phone.String
 		user.Sex = sex.String
-		user.Active = active.Bool
+		user.Status = status.String
 		user.CreatedAt = createdat.String
 		user.UpdatedAt = updatedat.String
 
 		resp.Users = append(resp.Users, user)
 	}
 
-	countQuery := `SELECT COUNT(id) FROM "Users"`
+	countQuery := `SELECT COUNT(id) FROM "Users" WHERE deleted_at IS NULL`
 	err = c.db.QueryRow(ctx, countQuery).Scan(&count)
 	resp.Count = count.Int64
 	if err != nil {
@@ -251,32 +279,212 @@ func (c *UserRepo) GetAll(ctx context.Context, req models.GetAllUsersRequest) (m
 	return resp, nil
 }
 
-func (c *UserRepo) Delete(ctx context.Context, id string) error {
-	query := `DELETE FROM "Users" WHERE id = $1`
+// Delete soft deletes the user. It can be restored until it is purged.
+func (c *UserRepo) Delete(ctx context.Context, id, actorID string) error {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		c.logger.Error("failed to begin delete user transaction", logger.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var from string
+
+	query := `SELECT status FROM "Users" WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
 
-	_, err := c.db.Exec(ctx, query, id)
+	err = tx.QueryRow(ctx, query, id).Scan(&from)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.ErrUserNotFound
+		}
+		c.logger.Error("failed to get user status from database", logger.Error(err))
+		return err
+	}
+
+	query = `UPDATE "Users" SET
+		status = $1,
+		status_reason = $2,
+		status_expires_at = NULL,
+		deleted_at = CURRENT_TIMESTAMP,
+		updated_at = CURRENT_TIMESTAMP
+	WHERE id = $3`
+
+	_, err = tx.Exec(ctx, query, models.StatusDeleted, userDeletedReason, id)
 	if err != nil {
 		c.logger.Error("failed to delete user from database", logger.Error(err))
 		return err
 	}
 
+	change := models.ChangeStatus{ID: id, Status: models.StatusDeleted, Reason: userDeletedReason}
+	if err = c.addStatusHistory(ctx, tx, change, from, actorID); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		c.logger.Error("failed to commit delete user transaction", logger.Error(err))
+		return err
+	}
+
 	return nil
 }
 
-func (c *UserRepo) ChangePassword(ctx context.Context, pass models.ChangePassword) (string, error) {
+// Restore undoes Delete for a user deleted after deletedAfter and returns the status the user is back in.
+func (c *UserRepo) Restore(ctx context.Context, id, actorID string, deletedAfter time.Time) (string, error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		c.logger.Error("failed to begin restore user transaction", logger.Error(err))
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var deletedAt *time.Time
+
+	query := `SELECT deleted_at FROM "Users" WHERE id = $1 FOR UPDATE`
+
+	err = tx.QueryRow(ctx, query, id).Scan(&deletedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", storage.ErrUserNotFound
+		}
+		c.logger.Error("failed to get deleted user from database", logger.Error(err))
+		return "", err
+	}
+	if deletedAt == nil {
+		return "", storage.ErrUserNotDeleted
+	}
+	if !deletedAt.After(deletedAfter) {
+		return "", storage.ErrRetentionExpired
+	}
+
+	// the user returns to the status it had before it was deleted
+	to := models.StatusActive
+
+	query = `SELECT from_status
+	FROM "UserStatusHistory"
+	WHERE user_id = $1 AND to_status = $2
+	ORDER BY created_at DESC
+	LIMIT 1`
+
+	err = tx.QueryRow(ctx, query, id, models.StatusDeleted).Scan(&to)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		c.logger.Error("failed to get status before deletion from database", logger.Error(err))
+		return "", err
+	}
+
+	query = `UPDATE "Users" SET
+		status = $1,
+		status_reason = $2,
+		deleted_at = NULL,
+		updated_at = CURRENT_TIMESTAMP
+	WHERE id = $3`
+
+	_, err = tx.Exec(ctx, query, to, userRestoredReason, id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return "", storage.ErrRestoreConflict
+		}
+		c.logger.Error("failed to restore user in database", logger.Error(err))
+		return "", err
+	}
+
+	change := models.ChangeStatus{ID: id, Status: to, Reason: userRestoredReason}
+	if err = c.addStatusHistory(ctx, tx, change, models.StatusDeleted, actorID); err != nil {
+		return "", err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		c.logger.Error("failed to commit restore user transaction", logger.Error(err))
+		return "", err
+	}
+
+	return to, nil
+}
+
+// Purge removes users deleted before deletedBefore and returns how many were purged.
+// With anonymise the row and its status history are kept for audits, but personal data,
+// credentials, sessions and roles are wiped.
+func (c *UserRepo) Purge(ctx context.Context, deletedBefore time.Time, anonymise bool) (int64, error) {
+	if !anonymise {
+		tag, err := c.db.Exec(ctx, `DELETE FROM "Users" WHERE deleted_at < $1`, deletedBefore)
+		if err != nil {
+			c.logger.Error("failed to purge deleted users from database", logger.Error(err))
+			return 0, err
+		}
+		return tag.RowsAffected(), nil
+	}
+
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		c.logger.Error("failed to begin purge users transaction", logger.Error(err))
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE "Users" SET
+		mail = NULL,
+		first_name = '',
+		last_name = NULL,
+		password = '',
+		phone = NULL,
+		sex = '',
+		status_reason = NULL,
+		anonymised_at = CURRENT_TIMESTAMP
+	WHERE deleted_at < $1 AND anonymised_at IS NULL
+	RETURNING id`
+
+	rows, err := tx.Query(ctx, query, deletedBefore)
+	if err != nil {
+		c.logger.Error("failed to anonymise deleted users in database", logger.Error(err))
+		return 0, err
+	}
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			c.logger.Error("failed to scan anonymised user id", logger.Error(err))
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		c.logger.Error("failed to anonymise deleted users in database", logger.Error(err))
+		return 0, err
+	}
+
+	for _, table := range []string{"UserRoles", "Sessions", "UserTotp", "RecoveryCodes", "PasswordHistory"} {
+		_, err := tx.Exec(ctx, `DELETE FROM "`+table+`" WHERE user_id = ANY($1)`, ids)
+		if err != nil {
+			c.logger.Error("failed to delete data of anonymised users from database", logger.String("table", table), logger.Error(err))
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		c.logger.Error("failed to commit purge users transaction", logger.Error(err))
+		return 0, err
+	}
+
+	return int64(len(ids)), nil
+}
+
+func (c *UserRepo) ChangePassword(ctx context.Context, id string, pass models.ChangePassword) (string, error) {
 	var hashedPass string
 
 	query := `SELECT password
 	FROM "Users"
-	WHERE mail = $1`
+	WHERE id = $1 AND deleted_at IS NULL`
 
 	err := c.db.QueryRow(ctx, query,
-		pass.Mail,
+		id,
 	).Scan(&hashedPass)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return "", errors.New("incorrect mail")
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", storage.ErrUserNotFound
 		}
 		c.logger.Error("failed to get user password from database", logger.Error(err))
 		return "", err
@@ -284,8 +492,11 @@ func (c *UserRepo) ChangePassword(ctx context.Context, pass models.ChangePasswor
 
 	err = password.CompareHashAndPassword(hashedPass, pass.OldPassword)
 	if err != nil {
-		fmt.Println(err)
-		return "", errors.New("password mismatch")
+		return "", storage.ErrInvalidCredentials
+	}
+
+	if err = c.checkPasswordReuse(ctx, id, hashedPass, pass.NewPassword); err != nil {
+		return "", err
 	}
 
 	newHashedPassword, err := password.HashPassword(pass.NewPassword)
@@ -294,14 +505,7 @@ func (c *UserRepo) ChangePassword(ctx context.Context, pass models.ChangePasswor
 		return "", err
 	}
 
-	query = `UPDATE "Users" SET 
-		password = $1, 
-		updated_at = CURRENT_TIMESTAMP 
-	WHERE mail = $2`
-
-	_, err = c.db.Exec(ctx, query, newHashedPassword, pass.Mail)
-	if err != nil {
-		c.logger.Error("failed to change user password in database", logger.Error(err))
+	if err = c.setPassword(ctx, id, newHashedPassword); err != nil {
 		return "", err
 	}
 
@@ -310,7 +514,7 @@ func (c *UserRepo) ChangePassword(ctx context.Context, pass models.ChangePasswor
 
 func (c *UserRepo) CheckMailExists(ctx context.Context, mail string) (string, error) {
 	var exists string
-	query := `SELECT mail FROM "Users" WHERE mail = $1`
+	query := `SELECT mail FROM "Users" WHERE mail = $1 AND deleted_at IS NULL`
 	err := c.db.QueryRow(ctx, query, mail).Scan(&exists)
 	if err != nil {
 		c.logger.Error("failed to check if email exists", logger.Error(err))
@@ -319,65 +523,406 @@ func (c *UserRepo) CheckMailExists(ctx context.Context, mail string) (string, er
 	return exists, nil
 }
 
+func (c *UserRepo) GetByMail(ctx context.Context, mail string) (models.User, error) {
+	var (
+		user      models.User
+		firstname sql.NullString
+		lastname  sql.NullString
+		phone     sql.NullString
+		sex       sql.NullString
+		status    sql.NullString
+		createdat sql.NullString
+		updatedat sql.NullString
+	)
+
+	query := `SELECT 
+		id,
+		mail,
+		first_name,
+		last_name,
+		phone,
+		sex,
+		status,
+		created_at,
+		updated_at
+	FROM "Users" 
+	WHERE mail = $1 AND deleted_at IS NULL`
+
+	err := c.db.QueryRow(ctx, query, mail).Scan(
+		&user.ID,
+		&user.Mail,
+		&firstname,
+		&lastname,
+		&phone,
+		&sex,
+		&status,
+		&createdat,
+		&updatedat,
+	)
+	if err != nil {
+		c.logger.Error("failed to scan user by mail from database", logger.Error(err))
+		return models.User{}, err
+	}
+
+	user.FirstName = firstname.String
+	user.LastName = lastname.String
+	user.Phone = phone.String
+	user.Sex = sex.String
+	user.Status = status.String
+	user.CreatedAt = createdat.String
+	user.UpdatedAt = updatedat.String
+
+	return user, nil
+}
+
 func (c *UserRepo) ForgetPassword(ctx context.Context, forget models.ForgetPassword) (string, error) {
+	var id, hashedPass string
 
-	query := `UPDATE "Users" SET 
-		password = $1, 
-		updated_at = CURRENT_TIMESTAMP 
-	WHERE mail = $2`
+	query := `SELECT id, password
+	FROM "Users"
+	WHERE mail = $1 AND deleted_at IS NULL`
+
+	err := c.db.QueryRow(ctx, query, forget.Mail).Scan(&id, &hashedPass)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", errors.New("user not found")
+		}
+		c.logger.Error("failed to get user password from database", logger.Error(err))
+		return "", err
+	}
 
-	_, err := c.db.Exec(ctx, query, forget.NewPassword, forget.Mail)
+	if err = c.checkPasswordReuse(ctx, id, hashedPass, forget.NewPassword); err != nil {
+		return "", err
+	}
 
+	newHashedPassword, err := password.HashPassword(forget.NewPassword)
 	if err != nil {
-		c.logger.Error("failed to update user password in database", logger.Error(err))
+		c.logger.Error("failed to generate User new password", logger.Error(err))
+		return "", err
+	}
+
+	if err = c.setPassword(ctx, id, newHashedPassword); err != nil {
 		return "", err
 	}
 
 	return "Password changed successfully", nil
 }
 
-func (c *UserRepo) ChangeStatus(ctx context.Context, status models.ChangeStatus) (string, error) {
-	query := `UPDATE "Users" SET 
-		active = $1, 
-		updated_at = CURRENT_TIMESTAMP 
+// checkPasswordReuse returns storage.ErrPasswordReused if plain matches the current
+// password hash or one of the user's last passwordHistory passwords.
+func (c *UserRepo) checkPasswordReuse(ctx context.Context, id, currentHash, plain string) error {
+	if c.passwordHistory <= 0 {
+		return nil
+	}
+
+	hashes := []string{currentHash}
+
+	query := `SELECT password
+	FROM "PasswordHistory"
+	WHERE user_id = $1
+	ORDER BY created_at DESC
+	LIMIT $2`
+
+	rows, err := c.db.Query(ctx, query, id, c.passwordHistory)
+	if err != nil {
+		c.logger.Error("failed to get password history from database", logger.Error(err))
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			c.logger.Error("failed to scan password history from database", logger.Error(err))
+			return err
+		}
+		hashes = append(hashes, hash)
+	}
+
+	for _, hash := range hashes {
+		if password.CompareHashAndPassword(hash, plain) == nil {
+			return storage.ErrPasswordReused
+		}
+	}
+
+	return nil
+}
+
+// setPassword stores a new password hash and records it in the password history.
+func (c *UserRepo) setPassword(ctx context.Context, id, hash string) error {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		c.logger.Error("failed to begin change password transaction", logger.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE "Users" SET
+		password = $1,
+		password_changed_at = CURRENT_TIMESTAMP,
+		updated_at = CURRENT_TIMESTAMP
 	WHERE id = $2`
 
-	_, err := c.db.Exec(ctx, query, status.Active, status.ID)
+	_, err = tx.Exec(ctx, query, hash, id)
+	if err != nil {
+		c.logger.Error("failed to change user password in database", logger.Error(err))
+		return err
+	}
+
+	if err = c.addPasswordHistory(ctx, tx, id, hash); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		c.logger.Error("failed to commit change password transaction", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// addPasswordHistory records hash and drops history entries beyond passwordHistory.
+func (c *UserRepo) addPasswordHistory(ctx context.Context, tx pgx.Tx, id, hash string) error {
+	if c.passwordHistory <= 0 {
+		return nil
+	}
+
+	query := `INSERT INTO "PasswordHistory" (
+		id,
+		user_id,
+		password,
+		created_at
+	) VALUES ($1, $2, $3, CURRENT_TIMESTAMP)`
+
+	_, err := tx.Exec(ctx, query, uuid.New().String(), id, hash)
+	if err != nil {
+		c.logger.Error("failed to add password history in database", logger.Error(err))
+		return err
+	}
+
+	query = `DELETE FROM "PasswordHistory"
+	WHERE user_id = $1 AND id NOT IN (
+		SELECT id FROM "PasswordHistory"
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	)`
+
+	_, err = tx.Exec(ctx, query, id, c.passwordHistory)
+	if err != nil {
+		c.logger.Error("failed to trim password history in database", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// ChangeStatus moves the user from status from to status.Status and records the transition.
+// It fails with storage.ErrStatusChanged if the user is no longer in status from.
+func (c *UserRepo) ChangeStatus(ctx context.Context, status models.ChangeStatus, from, actorID string) (string, error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		c.logger.Error("failed to begin change status transaction", logger.Error(err))
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE "Users" SET
+		status = $1,
+		status_reason = $2,
+		status_expires_at = $3,
+		deleted_at = CASE WHEN $1 = 'deleted' THEN CURRENT_TIMESTAMP END,
+		updated_at = CURRENT_TIMESTAMP
+	WHERE id = $4 AND status = $5`
+
+	tag, err := tx.Exec(ctx, query, status.Status, status.Reason, status.ExpiresAt, status.ID, from)
 	if err != nil {
 		c.logger.Error("failed to change user status in database", logger.Error(err))
 		return "", err
 	}
+	if tag.RowsAffected() == 0 {
+		return "", storage.ErrStatusChanged
+	}
+
+	if err = c.addStatusHistory(ctx, tx, status, from, actorID); err != nil {
+		return "", err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		c.logger.Error("failed to commit change status transaction", logger.Error(err))
+		return "", err
+	}
 
 	return status.ID, nil
 }
 
-func (c *UserRepo) LoginByMailAndPassword(ctx context.Context, login models.UserLoginRequest) (string, error) {
+// addStatusHistory records the transition of a user from status from to status.Status.
+// An empty actorID means the change was made by the system.
+func (c *UserRepo) addStatusHistory(ctx context.Context, tx pgx.Tx, status models.ChangeStatus, from, actorID string) error {
+	query := `INSERT INTO "UserStatusHistory" (
+		id,
+		user_id,
+		from_status,
+		to_status,
+		reason,
+		actor_id,
+		expires_at,
+		created_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP)`
+
+	var actor *string
+	if actorID != "" {
+		actor = &actorID
+	}
+
+	_, err := tx.Exec(ctx, query, uuid.New().String(), status.ID, from, status.Status, status.Reason, actor, status.ExpiresAt)
+	if err != nil {
+		c.logger.Error("failed to add user status history in database", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (c *UserRepo) GetStatus(ctx context.Context, id string) (models.UserStatus, error) {
+	var (
+		status models.UserStatus
+		reason sql.NullString
+	)
+
+	query := `SELECT
+		status,
+		status_reason,
+		status_expires_at
+	FROM "Users"
+	WHERE id = $1`
+
+	err := c.db.QueryRow(ctx, query, id).Scan(&status.Status, &reason, &status.ExpiresAt)
+	if err != nil {
+		c.logger.Error("failed to get user status from database", logger.Error(err))
+		return models.UserStatus{}, err
+	}
+	status.Reason = reason.String
+
+	return status, nil
+}
+
+func (c *UserRepo) GetStatusHistory(ctx context.Context, id string) (models.GetStatusHistoryResponse, error) {
 	var (
+		resp    = models.GetStatusHistoryResponse{}
+		reason  sql.NullString
+		actorID sql.NullString
+	)
+
+	query := `SELECT
+		id,
+		user_id,
+		from_status,
+		to_status,
+		reason,
+		actor_id,
+		expires_at,
+		created_at
+	FROM "UserStatusHistory"
+	WHERE user_id = $1
+	ORDER BY created_at DESC`
+
+	rows, err := c.db.Query(ctx, query, id)
+	if err != nil {
+		c.logger.Error("failed to get user status history from database", logger.Error(err))
+		return resp, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var change models.StatusChange
+
+		err := rows.Scan(
+			&change.ID,
+			&change.UserID,
+			&change.FromStatus,
+			&change.ToStatus,
+			&reason,
+			&actorID,
+			&change.ExpiresAt,
+			&change.CreatedAt,
+		)
+		if err != nil {
+			c.logger.Error("failed to scan user status history from database", logger.Error(err))
+			return models.GetStatusHistoryResponse{}, err
+		}
+
+		change.Reason = reason.String
+		change.ActorID = actorID.String
+
+		resp.History = append(resp.History, change)
+	}
+	resp.Count = int64(len(resp.History))
+
+	return resp, nil
+}
+
+func (c *UserRepo) LoginByMailAndPassword(ctx context.Context, login models.UserLoginRequest) (models.AuthUser, error) {
+	var (
+		user models.AuthUser
 		pswd string
 	)
 
 	query := `SELECT
-        password
-    FROM "Users" 
-    WHERE mail = $1`
+		u.id,
+		u.mail,
+		u.password,
+		COALESCE(u.password_changed_at, u.created_at, CURRENT_TIMESTAMP),
+		` + userRolesColumns + `
+	FROM "Users" u
+	WHERE u.mail = $1 AND u.deleted_at IS NULL`
 
 	row := c.db.QueryRow(ctx, query, login.Mail)
 	err := row.Scan(
+		&user.ID,
+		&user.Mail,
 		&pswd,
+		&user.PasswordChangedAt,
+		&user.Roles,
+		&user.Permissions,
 	)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return "", err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.AuthUser{}, storage.ErrInvalidCredentials
 		}
 		c.logger.Error("failed to scan user by email from database", logger.Error(err))
-		return "", err
+		return models.AuthUser{}, err
 	}
 
 	err = password.CompareHashAndPassword(pswd, login.Password)
 	if err != nil {
-		return "", errors.New("password mismatch")
+		return models.AuthUser{}, storage.ErrInvalidCredentials
 	}
 
-	return login.Mail, nil
+	if password.NeedsRehash(pswd) {
+		c.rehashPassword(ctx, user.ID, pswd, login.Password)
+	}
+
+	return user, nil
+}
+
+// rehashPassword upgrades a verified password's hash to the preferred algorithm and cost.
+// Failures are only logged, the old hash keeps working.
+func (c *UserRepo) rehashPassword(ctx context.Context, id, oldHash, plain string) {
+	newHash, err := password.HashPassword(plain)
+	if err != nil {
+		c.logger.Error("failed to rehash password", logger.Error(err))
+		return
+	}
+
+	query := `UPDATE "Users" SET
+		password = $1,
+		updated_at = CURRENT_TIMESTAMP
+	WHERE id = $2 AND password = $3`
+
+	_, err = c.db.Exec(ctx, query, newHash, id, oldHash)
+	if err != nil {
+		c.logger.Error("failed to update rehashed password in database", logger.Error(err))
+	}
 }
@@ -53,3 +53,61 @@ func (s Store) Del(ctx context.Context, key string) error {
 	fmt.Println("Deleted from redis cache")
 	return nil
 }
+
+func (s Store) SetNX(ctx context.Context, key string, value interface{}, duration time.Duration) (bool, error) {
+	boolCmd := s.db.SetNX(ctx, key, value, duration)
+	if boolCmd.Err() != nil {
+		return false, boolCmd.Err()
+	}
+	return boolCmd.Val(), nil
+}
+
+func (s Store) Exists(ctx context.Context, key string) (bool, error) {
+	intCmd := s.db.Exists(ctx, key)
+	if intCmd.Err() != nil {
+		return false, intCmd.Err()
+	}
+	return intCmd.Val() > 0, nil
+}
+
+// SAdd adds member to the set stored at key and resets the set's expiry.
+func (s Store) SAdd(ctx context.Context, key string, member interface{}, duration time.Duration) error {
+	pipe := s.db.TxPipeline()
+	pipe.SAdd(ctx, key, member)
+	pipe.Expire(ctx, key, duration)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s Store) SMembers(ctx context.Context, key string) ([]string, error) {
+	resp := s.db.SMembers(ctx, key)
+	if resp.Err() != nil {
+		return nil, resp.Err()
+	}
+	return resp.Val(), nil
+}
+
+// Incr increments the counter stored at key. The expiry is set when the counter is created.
+func (s Store) Incr(ctx context.Context, key string, duration time.Duration) (int64, error) {
+	intCmd := s.db.Incr(ctx, key)
+	if intCmd.Err() != nil {
+		return 0, intCmd.Err()
+	}
+
+	if intCmd.Val() == 1 {
+		if err := s.db.Expire(ctx, key, duration).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return intCmd.Val(), nil
+}
+
+// TTL returns the remaining time to live of key, or a non-positive duration if it has none.
+func (s Store) TTL(ctx context.Context, key string) (time.Duration, error) {
+	durationCmd := s.db.TTL(ctx, key)
+	if durationCmd.Err() != nil {
+		return 0, durationCmd.Err()
+	}
+	return durationCmd.Val(), nil
+}
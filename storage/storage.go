@@ -2,14 +2,37 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"user/api/models"
 
 	"time"
 )
 
+// ErrInvalidCredentials is returned by LoginByMailAndPassword for an unknown mail or a wrong password
+// and by ChangePassword for a wrong old password.
+var ErrInvalidCredentials = errors.New("invalid mail or password")
+
+// ErrPasswordReused is returned when a new password matches one of the user's recent passwords.
+var ErrPasswordReused = errors.New("password was used recently, choose a different one")
+
+// ErrStatusChanged is returned by ChangeStatus when the user's status changed in the meantime.
+var ErrStatusChanged = errors.New("user status was changed concurrently")
+
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrUserNotDeleted   = errors.New("user is not deleted")
+	ErrRetentionExpired = errors.New("user was deleted too long ago to be restored")
+	// ErrRestoreConflict is returned by Restore when another user took the mail or phone in the meantime.
+	ErrRestoreConflict = errors.New("mail or phone of the deleted user is used by another user")
+)
+
 type IStorage interface {
 	CloseDB()
 	User() IUserStorage
+	Session() ISessionStorage
+	Role() IRoleStorage
+	Totp() ITotpStorage
+	RecoveryCode() IRecoveryCodeStorage
 	Redis() IRedisStorage
 }
 
@@ -18,17 +41,58 @@ type IUserStorage interface {
 	Update(ctx context.Context, User models.UpdateUser, id string) (string, error)
 	GetByID(ctx context.Context, id string) (models.User, error)
 	GetAll(ctx context.Context, req models.GetAllUsersRequest) (models.GetAllUsersResponse, error)
-	Delete(ctx context.Context, id string) error
+	Delete(ctx context.Context, id, actorID string) error
+	Restore(ctx context.Context, id, actorID string, deletedAfter time.Time) (string, error)
+	Purge(ctx context.Context, deletedBefore time.Time, anonymise bool) (int64, error)
 	
-	ChangePassword(ctx context.Context, pass models.ChangePassword) (string, error)
+	ChangePassword(ctx context.Context, id string, pass models.ChangePassword) (string, error)
 	CheckMailExists(ctx context.Context, mail string) (string, error)
+	GetByMail(ctx context.Context, mail string) (models.User, error)
 	ForgetPassword(ctx context.Context, forget models.ForgetPassword) (string, error)
-	ChangeStatus(ctx context.Context, status models.ChangeStatus) (string, error)
-	LoginByMailAndPassword(ctx context.Context, login models.UserLoginRequest) (string, error) 
+	ChangeStatus(ctx context.Context, status models.ChangeStatus, from, actorID string) (string, error)
+	GetStatus(ctx context.Context, id string) (models.UserStatus, error)
+	GetStatusHistory(ctx context.Context, id string) (models.GetStatusHistoryResponse, error)
+	LoginByMailAndPassword(ctx context.Context, login models.UserLoginRequest) (models.AuthUser, error)
+}
+
+type ISessionStorage interface {
+	Create(ctx context.Context, session models.CreateSession) (string, error)
+	GetByID(ctx context.Context, id string) (models.Session, error)
+	GetAllByUserID(ctx context.Context, userID string, activeSince time.Time) (models.GetSessionsResponse, error)
+	UpdateLastSeen(ctx context.Context, family string) error
+	Delete(ctx context.Context, id string) error
+	DeleteByFamily(ctx context.Context, family string) error
+	DeleteByUserID(ctx context.Context, userID, exceptFamily string) error
+}
+
+type IRoleStorage interface {
+	GetByUserID(ctx context.Context, userID string) (models.UserRoles, error)
+	Assign(ctx context.Context, userID, role string) error
+}
+
+type ITotpStorage interface {
+	Upsert(ctx context.Context, userID, secret string) error
+	GetByUserID(ctx context.Context, userID string) (models.UserTotp, error)
+	Confirm(ctx context.Context, userID string) error
+	Delete(ctx context.Context, userID string) error
+}
+
+type IRecoveryCodeStorage interface {
+	Replace(ctx context.Context, userID string, hashes []string) error
+	GetUnused(ctx context.Context, userID string) ([]models.RecoveryCode, error)
+	CountUnused(ctx context.Context, userID string) (int64, error)
+	MarkUsed(ctx context.Context, id string) (bool, error)
+	DeleteByUserID(ctx context.Context, userID string) error
 }
 
 type IRedisStorage interface {
 	Set(ctx context.Context, key string, value interface{}, duration time.Duration) error
 	Get(ctx context.Context, key string) (interface{}, error)
 	Del(ctx context.Context, key string) error
+	SetNX(ctx context.Context, key string, value interface{}, duration time.Duration) (bool, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	SAdd(ctx context.Context, key string, member interface{}, duration time.Duration) error
+	SMembers(ctx context.Context, key string) ([]string, error)
+	Incr(ctx context.Context, key string, duration time.Duration) (int64, error)
+	TTL(ctx context.Context, key string) (time.Duration, error)
 }
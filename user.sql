@@ -1,12 +1,88 @@
 CREATE TABLE "Users" (
   "id" uuid PRIMARY KEY,
-  "mail" VARCHAR(50) UNIQUE,
+  "mail" VARCHAR(50),
   "first_name" VARCHAR(50) NOT NULL,
   "last_name" VARCHAR(50),
   "password" VARCHAR(255) NOT NULL,
-  "phone" VARCHAR(20) UNIQUE,
+  "phone" VARCHAR(20),
   "sex" VARCHAR(20) NOT NULL,
-  "active" BOOLEAN NOT NULL DEFAULT true,
+  "status" VARCHAR(30) NOT NULL DEFAULT 'active',
+  "status_reason" VARCHAR(255),
+  "status_expires_at" TIMESTAMP,
+  "deleted_at" TIMESTAMP,
+  "anonymised_at" TIMESTAMP,
+  "password_changed_at" TIMESTAMP,
   "created_at" TIMESTAMP,
   "updated_at" TIMESTAMP
-);
\ No newline at end of file
+);
+
+CREATE INDEX "users_deleted_at_idx" ON "Users"("deleted_at") WHERE "deleted_at" IS NOT NULL;
+CREATE UNIQUE INDEX "users_mail_key" ON "Users"("mail") WHERE "deleted_at" IS NULL;
+CREATE UNIQUE INDEX "users_phone_key" ON "Users"("phone") WHERE "deleted_at" IS NULL;
+
+CREATE TABLE "Sessions" (
+  "id" uuid PRIMARY KEY,
+  "user_id" uuid NOT NULL REFERENCES "Users"("id") ON DELETE CASCADE,
+  "refresh_family" uuid UNIQUE NOT NULL,
+  "user_agent" VARCHAR(255),
+  "ip" VARCHAR(45),
+  "created_at" TIMESTAMP,
+  "last_seen_at" TIMESTAMP
+);
+
+CREATE INDEX "sessions_user_id_idx" ON "Sessions"("user_id");
+
+CREATE TABLE "Roles" (
+  "id" SERIAL PRIMARY KEY,
+  "name" VARCHAR(50) UNIQUE NOT NULL,
+  "permissions" TEXT[] NOT NULL DEFAULT '{}'
+);
+
+CREATE TABLE "UserRoles" (
+  "user_id" uuid NOT NULL REFERENCES "Users"("id") ON DELETE CASCADE,
+  "role_id" INT NOT NULL REFERENCES "Roles"("id") ON DELETE CASCADE,
+  PRIMARY KEY ("user_id", "role_id")
+);
+
+INSERT INTO "Roles" ("name", "permissions") VALUES
+  ('admin', '{users:read,users:write,users:status}'),
+  ('user', '{}');
+
+CREATE TABLE "UserTotp" (
+  "user_id" uuid PRIMARY KEY REFERENCES "Users"("id") ON DELETE CASCADE,
+  "secret" TEXT NOT NULL,
+  "confirmed_at" TIMESTAMP,
+  "created_at" TIMESTAMP
+);
+
+CREATE TABLE "RecoveryCodes" (
+  "id" uuid PRIMARY KEY,
+  "user_id" uuid NOT NULL REFERENCES "Users"("id") ON DELETE CASCADE,
+  "code_hash" VARCHAR(255) NOT NULL,
+  "used_at" TIMESTAMP,
+  "created_at" TIMESTAMP
+);
+
+CREATE INDEX "recovery_codes_user_id_idx" ON "RecoveryCodes"("user_id");
+
+CREATE TABLE "PasswordHistory" (
+  "id" uuid PRIMARY KEY,
+  "user_id" uuid NOT NULL REFERENCES "Users"("id") ON DELETE CASCADE,
+  "password" VARCHAR(255) NOT NULL,
+  "created_at" TIMESTAMP
+);
+
+CREATE INDEX "password_history_user_id_idx" ON "PasswordHistory"("user_id", "created_at");
+
+CREATE TABLE "UserStatusHistory" (
+  "id" uuid PRIMARY KEY,
+  "user_id" uuid NOT NULL REFERENCES "Users"("id") ON DELETE CASCADE,
+  "from_status" VARCHAR(30) NOT NULL,
+  "to_status" VARCHAR(30) NOT NULL,
+  "reason" VARCHAR(255),
+  "actor_id" uuid,
+  "expires_at" TIMESTAMP,
+  "created_at" TIMESTAMP
+);
+
+CREATE INDEX "user_status_history_user_id_idx" ON "UserStatusHistory"("user_id", "created_at");
\ No newline at end of file